@@ -23,8 +23,9 @@
 package chainor
 
 import (
-	"sort"
+	"fmt"
 
+	"github.com/zeromicro/go-zero/core/threading"
 	"go.linecorp.com/garr/queue"
 )
 
@@ -33,6 +34,7 @@ func NewChainor(withFunc ...ChainorOption) *Chainor {
 	return &Chainor{
 		opt:   mergeOption[ChainorOption](withFunc...),
 		queue: queue.DefaultQueue(),
+		pools: &nodePools{},
 	}
 }
 
@@ -42,72 +44,187 @@ func (c *Chainor) Next(task TaskFunc, withFunc ...TaskOption) *Chainor {
 		return c
 	}
 
-	c.queue.Offer((&node{
+	return c.offer(&node{
 		opt:   mergeOption[TaskOption](withFunc...),
 		calls: TaskFuncs{task},
-	}).concrete())
+	})
+}
 
-	return c
+// nextFlow 注册由引擎调度的节点，例如 Switch、Switch2
+func (c *Chainor) nextFlow(flow flowFunc, withFunc ...TaskOption) *Chainor {
+	return c.offer(&node{
+		opt:  mergeOption[TaskOption](withFunc...),
+		flow: flow,
+	})
+}
+
+// child 子链路与原链路共享 Chainor 选项，但节点、Switch 等状态各自独立
+func (c *Chainor) child() *Chainor {
+	return &Chainor{
+		opt:   c.opt,
+		queue: queue.DefaultQueue(),
+		pools: c.pools,
+	}
 }
 
-// collateNode 该函数主要是对优先级进行排序和归类，目前优先级机制已废除，不过还是保留了这部分代码，阅读代码时可略过该部分
-func collateNode(name string, cb func(withfuncs TaskFuncs)) {
-	tempMap := newSyncMapBucket()
-	keyArray := make([]int, 0, nodeMap.lenB(name))
+// compile 将 cb 注册的子链路构建为执行计划，错误记录到原链路
+func (c *Chainor) compile(cb Case) *Plan {
+	nc := c.child()
+	cb(nc)
 
-	iter := nodeMap.iteratorB(name)
-	for iter.HasNext() {
-		n := iter.Next().(*node)
-		tempMap.put(n.priority, n)
+	p, err := nc.Build()
+	if err != nil {
+		c.fail(err)
 	}
+	return p
+}
 
-	tempMap.eachKey(func(bkey any) {
-		keyArray = append(keyArray, bkey.(int))
-	})
-	sort.SliceStable(keyArray, func(i, j int) bool {
-		return keyArray[i] > keyArray[j]
-	})
+func (c *Chainor) offer(n *node) *Chainor {
+	if c.forked {
+		c.fail(ErrChainForked)
+		return c
+	}
+
+	n, err := n.concrete(c.logger())
+	if err != nil {
+		c.fail(err)
+		return c
+	}
+	if n.workerpool != nil {
+		c.pools.add(n.workerpool)
+	}
+	c.queue.Offer(n)
 
-	for i := 0; i < len(keyArray); i++ {
-		iter = tempMap.iteratorB(keyArray[i])
-		var withs TaskFuncs
+	return c
+}
 
-		for iter.HasNext() {
-			n := iter.Next().(*node)
-			withs = append(withs, n.calls...)
-		}
-		cb(withs)
+// Release 释放链路（包括 Switch 的 case、循环体等子链路）上 WithWorkerPool 创建的节点协程池，由该链路 Build 生成的 Plan 同样失效
+//
+// 释放后再 Invoke，使用节点协程池的节点任务将返回 ants.ErrPoolClosed；按请求临时构建的链路在不再使用时应调用 Release
+func (c *Chainor) Release() {
+	c.pools.release()
+}
+
+// Err 返回构建链路时遇到的第一个错误，例如协程池创建失败、NextN 的任务名不存在等
+func (c *Chainor) Err() error {
+	return c.err
+}
+
+func (c *Chainor) fail(err error) {
+	if c.err == nil {
+		c.err = err
 	}
 }
 
-// NextN 通过任务名称注册链路上的节点任务，name 为 Register 注册的任务名
+func (c *Chainor) logger() Logger {
+	if c.opt.logger != nil {
+		return c.opt.logger
+	}
+	return globalLogger()
+}
+
+func (c *Chainor) registry() *Registry {
+	if c.opt.registry != nil {
+		return c.opt.registry
+	}
+	return defaultRegistry
+}
+
+// NextN 通过任务名称注册链路上的节点任务，name 为 Register 注册的任务名，从 Chainor 的注册表（见 WithRegistry）中获取
+//
+// 任务名下的每个优先级分组各自成为一个节点，详见 RegisterWithPriority
+// 构建时即固定任务名当前版本的任务函数，之后的 Replace、Unregister 不影响该链路
+// 指定 WithLazy 时改为每次执行时获取任务函数，见 WithLazy
 func (c *Chainor) NextN(name string, withFunc ...TaskOption) *Chainor {
-	if !nodeMap.exist(name) {
+	opt := mergeOption[TaskOption](withFunc...)
+	if opt.lazy {
+		return c.nextLazy(name, withFunc...)
+	}
+
+	snap, ok := c.registry().Lookup(name)
+	if !ok {
+		c.fail(fmt.Errorf("%w: %s", ErrTaskNotFound, name))
 		return c
 	}
 
-	// 优先级机制已废除，代码保留，此处所有任务的优先级都是相同的
-	collateNode(name, func(tasks TaskFuncs) {
-		withs := withFunc
-		if len(tasks) > 1 {
-			withs = append(withFunc, WithParallelFunc(tasks[1:]...))
+	snap.collate(func(i int, tasks TaskFuncs) {
+		calls := groupCalls(i, tasks, opt.funcs)
+		withs := append(withFunc, withGroup(snap, calls[1:]))
+		if i > 0 {
+			withs = append(withs, WithLabel(""))
 		}
-		c.Next(tasks[0], withs...)
+		c.Next(calls[0], withs...)
 	})
 	return c
 }
 
-func (c *Chainor) clone() *Chainor {
-	nc := *c
-	return &nc
+// nextLazy 注册延迟绑定的节点任务，执行时按任务名获取当前版本的任务函数，每个优先级分组依次作为一个子节点执行
+func (c *Chainor) nextLazy(name string, withFunc ...TaskOption) *Chainor {
+	r := c.registry()
+	n := &node{
+		opt: mergeOption[TaskOption](withFunc...),
+	}
+
+	n.flow = func(s *step) ([]any, error) {
+		snap, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+		}
+
+		var err error
+		lastRes := s.lastRes
+		snap.collate(func(i int, tasks TaskFuncs) {
+			if err != nil {
+				return
+			}
+
+			var res []any
+			gn := n.derive(snap, groupCalls(i, tasks, n.opt.funcs))
+			if res, err = (&step{
+				n:       gn,
+				p:       s.p,
+				f:       s.f,
+				lastRes: lastRes,
+			}).start(); err == nil && !gn.opt.skipResult {
+				lastRes = res
+			}
+		})
+		return lastRes, err
+	}
+	return c.offer(n)
+}
+
+// NextC 将另一条链路作为一个节点，other 可以是 *Chainor 或由 Build 生成的 *Plan，该节点的结果为 other 最终的结果
+//
+// other 在此时构建，构建错误记录到原链路；之后对 other 的修改不影响该节点
+// other 与原链路共享同一次调用的上下文值、超时及 Param、Props，任一节点失败则原链路以该错误失败
+func (c *Chainor) NextC(other Invocable, withFunc ...TaskOption) *Chainor {
+	p, err := other.Build()
+	if err != nil {
+		c.fail(err)
+		return c
+	}
+
+	return c.nextFlow(func(s *step) ([]any, error) {
+		return s.f.exec(p, s.lastRes)
+	}, withFunc...)
 }
 
 // Switch 条件节点，与 Switch2 不同的是此为单节点分支而非链路分支，可分可合
 func (c *Chainor) Switch(predicate Predicate) *switchCase {
-	return &switchCase{
+	return c.SwitchCtx(func(_ *TaskContext, lastResult []any) (any, error) {
+		return predicate(lastResult), nil
+	})
+}
+
+// SwitchCtx 同 Switch，predicate 可读取调用的 Param、Props 及上下文值，返回错误时链路以该错误失败
+func (c *Chainor) SwitchCtx(predicate ContextPredicate) *switchCase {
+	s := &switchCase{
 		predicate: predicate,
 		c:         c,
 	}
+	c.switches = append(c.switches, s)
+	return s
 }
 
 // Switch2 条件节点，可根据条件结果执行不同的链路，类似有向无环图（DAG），但只可分不可合
@@ -117,9 +234,61 @@ func (c *Chainor) Switch2(predicate Predicate) *switchCase2 {
 	}
 }
 
-// Invoke 触发 Chainor
+// Switch2Ctx 同 Switch2，predicate 可读取调用的 Param、Props 及上下文值，返回错误时链路以该错误失败
+func (c *Chainor) Switch2Ctx(predicate ContextPredicate) *switchCase2 {
+	return &switchCase2{
+		switchCase: c.SwitchCtx(predicate),
+	}
+}
+
+// Invoke 触发链路，c 可以是 *Chainor 或由 Build 生成的 *Plan
 //
 // onSucess 成功回调，onFailed 失败回调（内置错误定义在 types.go 里，包括超时、未命中等）
-func Invoke(c *Chainor, onSuccess func(result []any), onFailed func(err error), withFunc ...Option) {
-	c.newFuture(mergeOption[Option](withFunc...), onSuccess, onFailed).forward()
+// 链路校验失败时（见 Build）直接以该错误失败；通过 WithEngine 指定所属 Engine，Engine 已关闭时直接以 ErrShutdown 失败
+func Invoke(c Invocable, onSuccess func(result []any), onFailed func(err error), withFunc ...Option) {
+	var (
+		success func([]any, Values)
+		failed  func(error, Values)
+	)
+	if onSuccess != nil {
+		success = func(result []any, _ Values) {
+			onSuccess(result)
+		}
+	}
+	if onFailed != nil {
+		failed = func(err error, _ Values) {
+			onFailed(err)
+		}
+	}
+	invoke(c, success, failed, false, withFunc...)
+}
+
+// InvokeWithValues 与 Invoke 相同，回调额外收到链路结束时上下文值的只读快照，包括 WithValues 预置的值
+func InvokeWithValues(c Invocable, onSuccess func(result []any, values Values), onFailed func(err error, values Values), withFunc ...Option) {
+	invoke(c, onSuccess, onFailed, true, withFunc...)
+}
+
+func invoke(c Invocable, onSuccess func([]any, Values), onFailed func(error, Values), snapshot bool, withFunc ...Option) {
+	opt := mergeOption[Option](withFunc...)
+	if opt.engine == nil {
+		opt.engine = defaultEngine
+	}
+
+	p, err := c.Build()
+	if err == nil && !opt.engine.acquire(p) {
+		err = ErrShutdown
+	}
+	if err != nil {
+		// 与正常执行一致，回调始终异步触发
+		if onFailed != nil {
+			threading.GoSafe(func() {
+				onFailed(err, newValues(opt.values))
+			})
+		}
+		return
+	}
+
+	f := p.newFuture(opt, onSuccess, onFailed)
+	f.snapshot = snapshot
+	f.forward()
 }
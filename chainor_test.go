@@ -4,6 +4,7 @@ import (
 	"errors"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -593,8 +594,9 @@ func TestSwitch(t *testing.T) {
 			return 15, nil
 		}, WithTaskParam(90),
 		).CaseN(7, "s1", WithTaskParam(100), WithSkipped(func(result []any) bool {
-			// 测试是否会忽略
-			return true
+			// 命中的 case 仍按 WithSkipped 判定
+			c.So(result, ShouldResemble, []any{10})
+			return false
 		})).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
 			c.So(lastResult[0], ShouldEqual, 10)
 			c.So(ctx.Value("1"), ShouldEqual, "2")
@@ -696,8 +698,8 @@ func TestSwitch(t *testing.T) {
 			}).Switch(func(lastResult []any) (result any) {
 				return 7
 			}).CaseN(7, "s3", WithTaskParam(100), WithSkipped(func(result []any) bool {
-				// 测试是否会忽略
-				return true
+				// 命中的 case 仍按 WithSkipped 判定
+				return false
 			})).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
 				c.So(lastResult[0], ShouldEqual, 15)
 				return nil, errors.New("default err")
@@ -760,3 +762,613 @@ func TestSwitch(t *testing.T) {
 	})
 
 }
+
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) log(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, msg)
+}
+
+func (l *testLogger) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.msgs...)
+}
+
+func (l *testLogger) Debug(msg string, args ...any) { l.log(msg) }
+func (l *testLogger) Info(msg string, args ...any)  { l.log(msg) }
+func (l *testLogger) Warn(msg string, args ...any)  { l.log(msg) }
+func (l *testLogger) Error(msg string, args ...any) { l.log(msg) }
+
+func TestLogger(t *testing.T) {
+	Convey("Chainor logger", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		logger := &testLogger{}
+		chn := NewChainor(WithChainorLogger(logger)).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				panic("test panic")
+			}, WithWorkerPool(1))
+
+		So(chn.Err(), ShouldBeNil)
+
+		Invoke(chn, nil, func(err error) {
+			c.So(errors.Is(err, ErrPanic), ShouldBeTrue)
+			wg.Done()
+		})
+
+		wg.Wait()
+		So(logger.messages(), ShouldResemble, []string{"executor coroutine panic"})
+
+		Convey("Panic in default and parallel modes", func(c C) {
+			for _, withs := range [][]TaskOption{nil, {WithParallel(2)}} {
+				wg = sync.WaitGroup{}
+				wg.Add(1)
+
+				logger = &testLogger{}
+				chn = NewChainor(WithChainorLogger(logger)).
+					Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+						panic("test panic")
+					}, withs...)
+
+				Invoke(chn, nil, func(err error) {
+					c.So(errors.Is(err, ErrPanic), ShouldBeTrue)
+					wg.Done()
+				})
+
+				wg.Wait()
+				So(len(logger.messages()), ShouldBeGreaterThan, 0)
+				So(logger.messages()[0], ShouldEqual, "executor coroutine panic")
+			}
+		})
+	})
+}
+
+func TestResultOf(t *testing.T) {
+	Convey("Results of named nodes", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		Register("result-of", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return "registered", nil
+		})
+		defer Unregister("result-of")
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 1, nil
+		}, WithTaskName("first")).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 2, nil
+			}).
+			NextN("result-of").
+			Switch2(func(lastResult []any) (result any) {
+				return nil
+			}).Default(func(c2 *Chainor) {
+			c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				first, ok := ctx.ResultOf("first")
+				c.So(ok, ShouldBeTrue)
+				c.So(first, ShouldResemble, []any{1})
+
+				_, ok = ctx.ResultOf("unknown")
+				c.So(ok, ShouldBeFalse)
+
+				c.So(ctx.Results(), ShouldResemble, map[string][]any{
+					"first":     {1},
+					"result-of": {"registered"},
+				})
+				return first[0], nil
+			})
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{1})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
+
+func TestResultMapper(t *testing.T) {
+	Convey("Skip result and map result", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		Register("result-mapper", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0].(int) + 1, nil
+		})
+		defer Unregister("result-mapper")
+
+		double := func(result []any) []any {
+			return append(result, result...)
+		}
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return []any{1, 2}, nil
+			}, WithResultMapper(func(result []any) []any {
+				return result[0].([]any)
+			})).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(lastResult, ShouldResemble, []any{1, 2})
+				return "log", nil
+			}, WithSkipResult(), WithTaskName("log")).
+			NextN("result-mapper", WithLazy(), WithResultMapper(double)).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(lastResult, ShouldResemble, []any{2, 2})
+
+				log, _ := ctx.ResultOf("log")
+				c.So(log, ShouldResemble, []any{"log"})
+				return lastResult[0], nil
+			}, WithSkipped(func(result []any) bool {
+				return len(result) == 0
+			}), WithResultMapper(double))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{2, 2})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
+
+func TestSwitchMatch(t *testing.T) {
+	value := func(v any) TaskFunc {
+		return func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return v, nil
+		}
+	}
+
+	Convey("Switch with CaseWhen and CaseIn", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Switch(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).CaseIn([]any{1, 2}, value("in")).CaseWhen(func(result any) bool {
+			return result.(int) > 2
+		}, value("when")).Default(value("default"))
+
+		for param, expect := range map[int]string{2: "in", 5: "when", 0: "default"} {
+			expect := expect
+			Invoke(chn, func(result []any) {
+				c.So(result, ShouldResemble, []any{expect})
+				wg.Done()
+			}, nil, WithParam(param))
+		}
+
+		wg.Wait()
+	})
+
+	Convey("Switch All", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Switch(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).All().CaseWhen(func(result any) bool {
+			return result.(int) > 0
+		}, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			time.Sleep(100 * time.Millisecond)
+			return "positive", nil
+		}).Case(3, value("three")).CaseIn([]any{1, 2}, value("small")).
+			Default(value("default")).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult, nil
+			})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{"positive", "three"}})
+			wg.Done()
+		}, nil, WithParam(3))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{"default"}})
+			wg.Done()
+		}, nil, WithParam(-1))
+
+		wg.Wait()
+	})
+
+	Convey("Switch2 All", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).All().CaseIn([]any{1, 2}, func(c1 *Chainor) {
+			c1.Next(value("in")).Next(value("in2"))
+		}).CaseWhen(func(result any) bool {
+			return result.(int) < 2
+		}, func(c2 *Chainor) {
+			c2.Next(value("when"))
+		}).Case(2, func(c3 *Chainor) {
+			c3.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return nil, errors.New("case err")
+			})
+		}).Default(func(c4 *Chainor) {
+			c4.Next(value("default"))
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{"in2", "when"})
+			wg.Done()
+		}, nil, WithParam(1))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err.Error(), ShouldEqual, "case err")
+			wg.Done()
+		}, WithParam(2))
+
+		wg.Wait()
+	})
+}
+
+func TestSwitchEnd(t *testing.T) {
+	Convey("Switch without default", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		newChainor := func(strict bool) *Chainor {
+			s := NewChainor().Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return ctx.Param(), nil
+			}).Switch(func(lastResult []any) (result any) {
+				return lastResult[0]
+			}).Case(1, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "one", nil
+			})
+			if strict {
+				return s.EndStrict()
+			}
+			return s.End()
+		}
+
+		Invoke(newChainor(false), func(result []any) {
+			c.So(result, ShouldResemble, []any{"one"})
+			wg.Done()
+		}, nil, WithParam(1))
+
+		Invoke(newChainor(false), func(result []any) {
+			c.So(result, ShouldResemble, []any{2})
+			wg.Done()
+		}, nil, WithParam(2))
+
+		Invoke(newChainor(true), nil, func(err error) {
+			c.So(err, ShouldEqual, ErrNoCaseMatched)
+			wg.Done()
+		}, WithParam(2))
+
+		wg.Wait()
+	})
+
+	Convey("Switch2 without default", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).Case(1, func(c1 *Chainor) {
+			c1.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "one", nil
+			})
+		}).End()
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{2})
+			wg.Done()
+		}, nil, WithParam(2))
+
+		strict := NewChainor()
+		strict.Switch2(func(lastResult []any) (result any) {
+			return nil
+		}).EndStrict()
+
+		Invoke(strict, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrNoCaseMatched)
+			wg.Done()
+		})
+
+		wg.Wait()
+
+		_, err := chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, nil
+		}).Build()
+		So(err, ShouldEqual, ErrChainForked)
+	})
+}
+
+func TestSwitch2Nested(t *testing.T) {
+	Convey("Nested Switch and Switch2 in Switch2 branches", t, func(c C) {
+		var calls int32
+		wg := sync.WaitGroup{}
+		wg.Add(4)
+
+		param := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}
+
+		chn := NewChainor()
+		chn.Next(param).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0].(int) / 10
+		}).Case(1, func(c1 *Chainor) {
+			c1.Next(param).Switch(func(lastResult []any) (result any) {
+				return lastResult[0].(int) % 10
+			}).Case(1, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "1-1", nil
+			}).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "1-x", nil
+			}).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0].(string) + "!", nil
+			})
+		}).Default(func(c2 *Chainor) {
+			c2.Next(param).Switch2(func(lastResult []any) (result any) {
+				return lastResult[0].(int) % 10
+			}).Case(1, func(c3 *Chainor) {
+				c3.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return "x-1", nil
+				})
+			}).Default(func(c4 *Chainor) {
+				c4.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return nil, errors.New("nested err")
+				})
+			})
+		})
+
+		for param, expect := range map[int]any{11: "1-1!", 12: "1-x!", 21: "x-1", 22: errors.New("nested err")} {
+			expect := expect
+			Invoke(chn, func(result []any) {
+				atomic.AddInt32(&calls, 1)
+				c.So(result, ShouldResemble, []any{expect})
+				wg.Done()
+			}, func(err error) {
+				atomic.AddInt32(&calls, 1)
+				c.So(err, ShouldResemble, expect)
+				wg.Done()
+			}, WithParam(param))
+		}
+
+		wg.Wait()
+		time.Sleep(100 * time.Millisecond)
+		So(atomic.LoadInt32(&calls), ShouldEqual, 4)
+
+		Convey("Unterminated nested switch", func() {
+			chn = NewChainor()
+			chn.Switch2(func(lastResult []any) (result any) {
+				return nil
+			}).Default(func(c2 *Chainor) {
+				c2.Switch(func(lastResult []any) (result any) {
+					return nil
+				}).Case(1, param)
+			})
+
+			_, err := chn.Build()
+			So(err, ShouldEqual, ErrSwitchUnterminated)
+		})
+	})
+}
+
+func TestSwitchCtx(t *testing.T) {
+	Convey("Switch with context predicate", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			ctx.WithValue("route", ctx.Param())
+			return 1, nil
+		}).SwitchCtx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Props()["mode"], nil
+		}).Case("double", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0].(int) * 2, nil
+		}).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0], nil
+		}).Switch2Ctx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			route := ctx.Value("route")
+			if route == nil {
+				return nil, errors.New("no route")
+			}
+			return route, nil
+		}).Case("a", func(c1 *Chainor) {
+			c1.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0].(int) + 10, nil
+			})
+		}).Default(func(c2 *Chainor) {
+			c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0], nil
+			})
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{12})
+			wg.Done()
+		}, nil, WithParam("a"), WithProps(M{"mode": "double"}))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{1})
+			wg.Done()
+		}, nil, WithParam("b"))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err.Error(), ShouldEqual, "no route")
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+
+	Convey("Context predicate returns ErrStop", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		never := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, errors.New("never")
+		}
+		seven := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 7, nil
+		}
+
+		Invoke(NewChainor().Next(seven).SwitchCtx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, ErrStop
+		}).Default(never).Next(never), func(result []any) {
+			c.So(result, ShouldResemble, []any{7})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Next(seven).SwitchCtx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, ctx.Finish(8)
+		}).Default(never), func(result []any) {
+			c.So(result, ShouldResemble, []any{8})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
+
+func TestSwitchSkipped(t *testing.T) {
+	Convey("Matched case with WithSkipped", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Switch(func(lastResult []any) (result any) {
+			return lastResult[0].(int) > 0
+		}).Case(true, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return "positive", nil
+		}, WithSkipped(func(result []any) bool {
+			return result[0].(int) > 100
+		})).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return "other", nil
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{"positive"})
+			wg.Done()
+		}, nil, WithParam(1))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{101})
+			wg.Done()
+		}, nil, WithParam(101))
+
+		wg.Wait()
+	})
+}
+
+func TestFinish(t *testing.T) {
+	Convey("Finish a chain early", t, func(c C) {
+		var calls int32
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		never := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("never")
+		}
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Repeat(3, func(c1 *Chainor) {
+			c1.Switch2(func(lastResult []any) (result any) {
+				return lastResult[0]
+			}).Case("finish", func(c2 *Chainor) {
+				c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return nil, ctx.Finish("finished", 1)
+				})
+			}).Case("stop", func(c3 *Chainor) {
+				c3.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return nil, ErrStop
+				}, WithAnyPassed())
+			}).Default(func(c4 *Chainor) {
+				c4.Next(never)
+			})
+		}).Next(never)
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{"finished", 1})
+			wg.Done()
+		}, nil, WithParam("finish"))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{"stop"})
+			wg.Done()
+		}, nil, WithParam("stop"))
+
+		InvokeWithValues(NewChainor().Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			ctx.WithValue("k", "v")
+			return nil, ctx.Finish()
+		}).Next(never), func(result []any, values Values) {
+			c.So(result, ShouldBeEmpty)
+			c.So(values.Value("k"), ShouldEqual, "v")
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+		So(atomic.LoadInt32(&calls), ShouldEqual, 0)
+	})
+}
+
+func TestCallbackPanic(t *testing.T) {
+	Convey("Panic in user callbacks fails the chain", t, func(c C) {
+		task := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 1, nil
+		}
+
+		chns := []*Chainor{
+			NewChainor().Next(task, WithResultMapper(func(result []any) []any {
+				return result[5:]
+			})),
+			NewChainor().Next(task).SwitchCtx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				panic("predicate panic")
+			}).Default(task),
+			NewChainor().Next(task).While(func(lastResult []any) bool {
+				panic("condition panic")
+			}, func(c1 *Chainor) {
+				c1.Next(task)
+			}),
+		}
+
+		wg := sync.WaitGroup{}
+		wg.Add(len(chns))
+
+		logger := &testLogger{}
+		SetLogger(logger)
+		defer SetLogger(nil)
+
+		for _, chn := range chns {
+			Invoke(chn, func(result []any) {
+				c.So(result, ShouldBeNil)
+				wg.Done()
+			}, func(err error) {
+				c.So(errors.Is(err, ErrPanic), ShouldBeTrue)
+				wg.Done()
+			})
+		}
+
+		wg.Wait()
+		So(logger.messages(), ShouldHaveLength, len(chns))
+	})
+}
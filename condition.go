@@ -1,27 +1,43 @@
 package chainor
 
 import (
-	"go.linecorp.com/garr/queue"
+	"sync"
+
+	"github.com/zeromicro/go-zero/core/threading"
 )
 
 type (
 	// Predicate 返回 comparable 类型，用以 Case 匹配
 	Predicate func(lastResult []any) (result any)
 
+	// ContextPredicate 可读取调用上下文的 Predicate，返回错误时链路以该错误失败，见 SwitchCtx、Switch2Ctx
+	ContextPredicate func(ctx *TaskContext, lastResult []any) (result any, err error)
+
 	// Case case 分支的回调函数，回调函数内只能使用参数 c 注册分支链路
 	Case func(c *Chainor)
 
 	caseWrap struct {
-		expect   any
+		expect any
+		// when 不为空时以 when 判定是否命中，替代 expect 的相等比较
+		when     func(result any) bool
 		caseFunc any
 		opt      []TaskOption
 
-		c *Chainor
+		p *Plan
 	}
 
 	switchCase struct {
-		predicate Predicate
+		predicate ContextPredicate
 		cases     []*caseWrap
+		closed    bool
+		// all 执行所有命中的 case，而非第一个
+		all bool
+		// noDefault 以 End、EndStrict 结束，没有 default
+		noDefault bool
+		// strict 均未命中时返回 ErrNoCaseMatched，而非透传 lastResult
+		strict bool
+		// isolated 分支链路在隔离的上下文中执行，见 switchCase2 的 Isolate
+		isolated bool
 
 		c *Chainor
 	}
@@ -37,9 +53,9 @@ type (
 // task 为任务函数
 // withFunc 为可选项
 //
-// 注意！！ 所有 Case 和 Default 的 TaskOption 里用户自定义 WithSkipped 均不生效
+// 命中的 case 仍按 withFunc 里的 WithSkipped 判定，跳过时该节点透传 lastResult
 func (s *switchCase) Case(expect any, task TaskFunc, withFunc ...TaskOption) *switchCase {
-	s.cases = append(s.cases, &caseWrap{
+	s.add(&caseWrap{
 		expect:   expect,
 		caseFunc: task,
 		opt:      withFunc,
@@ -47,13 +63,38 @@ func (s *switchCase) Case(expect any, task TaskFunc, withFunc ...TaskOption) *sw
 	return s
 }
 
+// CaseWhen 条件节点下的 case 分支，当 when 对 Switch 的返回值判定为 true 时执行该分支
+func (s *switchCase) CaseWhen(when func(result any) bool, task TaskFunc, withFunc ...TaskOption) *switchCase {
+	s.add(&caseWrap{
+		when:     when,
+		caseFunc: task,
+		opt:      withFunc,
+	})
+	return s
+}
+
+// CaseIn 条件节点下的 case 分支，当 Switch 的返回值等于 values 中任意一个时执行该分支
+func (s *switchCase) CaseIn(values []any, task TaskFunc, withFunc ...TaskOption) *switchCase {
+	return s.CaseWhen(in(values), task, withFunc...)
+}
+
+// All 执行所有命中的 case，而非第一个命中的 case，均未命中时执行 default
+//
+// 命中的 case 并行执行，结果按 case 的注册顺序合并，任意 case 失败则该节点失败
+func (s *switchCase) All() *switchCase {
+	if !s.sealed() {
+		s.all = true
+	}
+	return s
+}
+
 // CaseN 条件节点下的 case 分支，当 Switch 的返回值等于 expect 时执行该分支
 //
 // expect 为 comparable 类型
 // name 为 Register 注册的任务名
 // withFunc 为可选项
 func (s *switchCase) CaseN(expect any, name string, withFunc ...TaskOption) *switchCase {
-	s.cases = append(s.cases, &caseWrap{
+	s.add(&caseWrap{
 		expect:   expect,
 		caseFunc: name,
 		opt:      withFunc,
@@ -61,60 +102,201 @@ func (s *switchCase) CaseN(expect any, name string, withFunc ...TaskOption) *swi
 	return s
 }
 
-func (s *switchCase) defaultF() *Chainor {
-	var expect any
-	caseLen := len(s.cases)
-	matched := false
+// add 注册 case，switch 已结束时记录错误而不注册
+func (s *switchCase) add(v *caseWrap) {
+	if !s.sealed() {
+		s.cases = append(s.cases, v)
+	}
+}
 
-	for i := 1; i <= caseLen; i++ {
-		v := s.cases[i-1]
+// sealed switch 是否已结束，已结束时记录 ErrSwitchClosed 到原链路
+func (s *switchCase) sealed() bool {
+	if s.closed {
+		s.c.fail(ErrSwitchClosed)
+	}
+	return s.closed
+}
+
+// compile 将每个 case 构建为独立的执行计划，错误记录到原链路
+//
+// 返回 switch 此时的副本用于执行，之后对 s 的任何修改都不影响已注册的节点及已构建的 Plan
+func (s *switchCase) compile() *switchCase {
+	s.closed = true
 
-		index := i
-		v.opt = append(v.opt, WithSkipped(func(result []any) bool {
-			if expect == nil {
-				expect = s.predicate(result)
+	for _, v := range s.cases {
+		v.p = s.c.compile(func(nc *Chainor) {
+			switch obj := v.caseFunc.(type) {
+			case TaskFunc:
+				nc.Next(obj, v.opt...)
+			case string:
+				nc.NextN(obj, v.opt...)
+			case Case:
+				obj(nc)
 			}
-			// 匹配到 case 或 default 的任一条件，其他跳过
-			if !matched &&
-				(index == caseLen || v.expect == expect) {
-				matched = true
-				return false
+		})
+	}
+
+	frozen := *s
+	frozen.cases = append([]*caseWrap(nil), s.cases...)
+	return &frozen
+}
+
+func in(values []any) func(result any) bool {
+	return func(result any) bool {
+		for _, v := range values {
+			if v == result {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (v *caseWrap) hit(expect any) bool {
+	if v.when != nil {
+		return v.when(expect)
+	}
+	return v.expect == expect
+}
+
+// match 匹配 case，均未命中时返回最后的 default，没有 default 时返回空；All 模式下返回所有命中的 case
+//
+// 匹配状态仅存在于单次调用内，同一个 Plan 并发 Invoke 时互不影响
+func (s *switchCase) match(st *step) ([]*caseWrap, error) {
+	expect, err := s.predicate(st.newTaskContext(), st.lastRes)
+	if err != nil {
+		// 同任务函数，直接返回 ErrStop 时以 lastResult 作为结果
+		if stop, ok := asStop(err, st.lastRes); ok {
+			err = stop
+		}
+		return nil, err
+	}
+	cases := s.cases
+	if !s.noDefault {
+		cases = cases[:len(cases)-1]
+	}
+
+	var matched []*caseWrap
+	for _, v := range cases {
+		if v.hit(expect) {
+			if !s.all {
+				return []*caseWrap{v}, nil
+			}
+			matched = append(matched, v)
+		}
+	}
+	if len(matched) == 0 && !s.noDefault {
+		return []*caseWrap{s.cases[len(s.cases)-1]}, nil
+	}
+	return matched, nil
+}
+
+// run 执行命中的 case，多个 case 时并行执行，结果按 case 顺序合并，错误取 case 顺序中的第一个
+//
+// 隔离的分支全部结束后，按 case 顺序回写成功结束的分支 Promote 标记的 key，结果与分支的完成先后无关
+func (s *switchCase) run(st *step) ([]any, error) {
+	matched, err := s.match(st)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(matched) == 0 && s.strict:
+		return nil, ErrNoCaseMatched
+	case len(matched) == 0:
+		return st.lastRes, nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		futures = make([]*future, len(matched))
+		ress    = make([][]any, len(matched))
+		errs    = make([]error, len(matched))
+	)
+	for i := range matched {
+		futures[i] = st.f
+		if s.isolated {
+			futures[i] = st.f.isolate()
+			defer futures[i].ctx.cancel()
+		}
+	}
+
+	if len(matched) == 1 {
+		ress[0], errs[0] = futures[0].exec(matched[0].p, st.lastRes)
+	} else {
+		wg.Add(len(matched))
+		for i, v := range matched {
+			i, p := i, v.p
+			threading.GoSafe(func() {
+				defer wg.Done()
+				ress[i], errs[i] = futures[i].exec(p, st.lastRes)
+			})
+		}
+		wg.Wait()
+	}
+
+	if s.isolated {
+		for i, f := range futures {
+			// 提前结束同样视为分支成功
+			if _, stopped := asStop(errs[i], nil); errs[i] == nil || stopped {
+				f.ctx.promote()
 			}
-			return true
-		}))
-
-		switch obj := v.caseFunc.(type) {
-		case TaskFunc:
-			s.c.Next(obj, v.opt...)
-		case string:
-			s.c.NextN(obj, v.opt...)
 		}
 	}
-	return s.c
+	if len(matched) == 1 {
+		return ress[0], errs[0]
+	}
+
+	var res []any
+	for i := range matched {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		res = append(res, ress[i]...)
+	}
+	return res, nil
+}
+
+func (s *switchCase) flow(st *step) ([]any, error) {
+	return s.run(st)
 }
 
-// Default Switch 语法必须以 Default 或 DefaultN 结束，否则 Switch 不会执行
+func (s *switchCase) defaultF() *Chainor {
+	return s.c.nextFlow(s.compile().flow)
+}
+
+// Default Switch 语法必须以 Default、DefaultN、End 或 EndStrict 结束，否则 Build 返回 ErrSwitchUnterminated
 //
-// 注意！！所有 Case 和 Default 的 TaskOption 里的用户自定义 WithSkipped 均不生效
+// 同 Case，default 分支仍按 withFunc 里的 WithSkipped 判定
 func (s *switchCase) Default(task TaskFunc, withFunc ...TaskOption) *Chainor {
+	if s.sealed() {
+		return s.c
+	}
 	return s.Case(nil, task, withFunc...).defaultF()
 }
 
-// DefaultN Switch 语法必须以 Default 或 DefaultN 结束，否则 Switch 不会执行
+// DefaultN 同 Default，default 分支为 Register 注册的任务
 func (s *switchCase) DefaultN(name string, withFunc ...TaskOption) *Chainor {
+	if s.sealed() {
+		return s.c
+	}
 	return s.CaseN(nil, name, withFunc...).defaultF()
 }
 
-func (s *switchCase2) newChainor() *Chainor {
-	nc := s.c.clone()
-	nc.queue = queue.DefaultQueue()
-	return nc
+// End 结束没有 default 的 Switch，均未命中时透传 lastResult
+func (s *switchCase) End() *Chainor {
+	if s.sealed() {
+		return s.c
+	}
+	s.noDefault = true
+	return s.defaultF()
 }
 
-func (s *switchCase2) newFuture(f *future, nc *Chainor) *future {
-	nf := f.clone()
-	nf.c = nc
-	return nf
+// EndStrict 结束没有 default 的 Switch，均未命中时返回 ErrNoCaseMatched
+func (s *switchCase) EndStrict() *Chainor {
+	if s.sealed() {
+		return s.c
+	}
+	s.strict = true
+	return s.End()
 }
 
 // Case 条件节点下的 case 分支
@@ -122,44 +304,90 @@ func (s *switchCase2) newFuture(f *future, nc *Chainor) *future {
 // expect 为 comparable 类型
 // cb 为注册的分支链路，当 Switch2 的返回值等于 expect 时执行该链路
 //
-// 注意！！cb 回调内仅支持注册节点任务，即只允许调用 Next、NextN
+// cb 回调内可使用 c 的全部能力，包括嵌套的 Switch、Switch2，嵌套的链路与原链路同步执行，
+// 全部结束后 Invoke 的回调有且仅有一次；嵌套 Switch 未结束等构建错误同样由原链路的 Build 返回
 func (s *switchCase2) Case(expect any, cb Case) *switchCase2 {
-	s.cases = append(s.cases, &caseWrap{
+	s.add(&caseWrap{
 		expect:   expect,
 		caseFunc: cb,
 	})
 	return s
 }
 
-// Default Switch2 语法必须以 Default 结束，否则 Switch2 不会执行
+// CaseWhen 条件节点下的 case 分支，当 when 对 Switch2 的返回值判定为 true 时执行 cb 注册的链路
 //
 // cb 同 Case 里的注释说明
+func (s *switchCase2) CaseWhen(when func(result any) bool, cb Case) *switchCase2 {
+	s.add(&caseWrap{
+		when:     when,
+		caseFunc: cb,
+	})
+	return s
+}
+
+// CaseIn 条件节点下的 case 分支，当 Switch2 的返回值等于 values 中任意一个时执行 cb 注册的链路
 //
-// 注意！！Default 后原链路彻底分叉，不可再对原 Chainor 继续 Next
-func (s *switchCase2) Default(cb Case) {
-	s.Case(nil, cb)
+// cb 同 Case 里的注释说明
+func (s *switchCase2) CaseIn(values []any, cb Case) *switchCase2 {
+	return s.CaseWhen(in(values), cb)
+}
 
-	for _, v := range s.cases {
-		v.c = s.newChainor()
-		v.caseFunc.(Case)(v.c)
+// All 执行所有命中的分支链路，均未命中时执行 default
+//
+// 命中的链路并行执行，链路的结果按 case 的注册顺序合并作为 Invoke 的结果，任意链路失败则 Invoke 失败
+func (s *switchCase2) All() *switchCase2 {
+	s.switchCase.All()
+	return s
+}
+
+// Isolate 分支链路在隔离的上下文中执行
+//
+// 分支内首次读取原链路的值时深拷贝一份，分支内的 WithValue、Delete 以及对拷贝值的修改均不影响原链路；
+// 需要回写原链路的 key 在分支内通过 TaskContext.Promote 标记，分支成功结束（包括 TaskContext.Finish 提前结束）时回写，分支失败时全部丢弃；
+// 分支内 TaskContext.Goto 跳转到原链路的节点时视为分支未成功结束，Promote 标记的 key 同样全部丢弃
+// All 模式下每个命中的链路各自隔离，全部结束后按 case 的注册顺序回写
+func (s *switchCase2) Isolate() *switchCase2 {
+	if !s.sealed() {
+		s.isolated = true
 	}
-	caseLen := len(s.cases)
+	return s
+}
 
-	s.c.Next(func(ctx *TaskContext, lastResult []any) (any, error) {
-		var err error
-		expect := s.predicate(lastResult)
+// Default Switch2 语法必须以 Default、End 或 EndStrict 结束，否则 Build 返回 ErrSwitchUnterminated
+//
+// cb 同 Case 里的注释说明
+//
+// 注意！！Default 后原链路彻底分叉，不可再对原 Chainor 继续 Next，否则 Build 返回 ErrChainForked
+func (s *switchCase2) Default(cb Case) {
+	if s.sealed() {
+		return
+	}
+	s.Case(nil, cb).end()
+}
 
-		for i := 1; i <= caseLen; i++ {
-			v := s.cases[i-1]
+// End 结束没有 default 的 Switch2，均未命中时透传 lastResult 作为 Invoke 的结果
+//
+// 注意！！同 Default，End 后不可再对原 Chainor 继续 Next
+func (s *switchCase2) End() {
+	if s.sealed() {
+		return
+	}
+	s.noDefault = true
+	s.end()
+}
 
-			// 匹配到 case 或 default 的任一条件，其他跳过
-			if i == caseLen || v.expect == expect {
-				s.newFuture(ctx.future(), v.c).forward(lastResult...)
-				err = errNotDone
-				break
-			}
-		}
+// EndStrict 结束没有 default 的 Switch2，均未命中时返回 ErrNoCaseMatched
+//
+// 注意！！同 Default，EndStrict 后不可再对原 Chainor 继续 Next
+func (s *switchCase2) EndStrict() {
+	if s.sealed() {
+		return
+	}
+	s.strict = true
+	s.End()
+}
 
-		return nil, err
-	}, withSkipReuslt())
+func (s *switchCase2) end() {
+	s.defaultF()
+	s.c.forked = true
 }
@@ -2,8 +2,11 @@ package chainor
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/huandu/go-clone"
 	cmp "github.com/orcaman/concurrent-map/v2"
 )
 
@@ -12,8 +15,24 @@ type (
 		ctx    context.Context
 		cancel context.CancelFunc
 
+		engine     *Engine
+		finishOnce sync.Once
+
 		f         *future
 		keyValues cmp.ConcurrentMap[any]
+		// results 具名节点的结果，见 TaskContext.ResultOf，隔离分支与原链路共享，Parallel 的分支各自独立
+		results cmp.ConcurrentMap[[]any]
+
+		// parent 分支（见 Switch2 的 Isolate、Parallel）的父上下文，为空时即为 Invoke 的上下文
+		parent *chainorContext
+		// deep 隔离分支，首次读取父上下文的值时深拷贝到分支内；否则为 Parallel 的分支，读取时直接读父上下文
+		deep bool
+		// promotes 隔离分支成功结束时需要回写到父上下文的 key
+		promotes cmp.ConcurrentMap[struct{}]
+		// dirty 分支内写入、删除过的 key，Parallel 的分支汇合时回写到父上下文
+		dirty cmp.ConcurrentMap[struct{}]
+		// hops 本次 Invoke 的跳转次数，所有分支共享
+		hops *int32
 	}
 
 	TaskContext struct {
@@ -23,17 +42,195 @@ type (
 
 func (f *future) newChainorContext() *chainorContext {
 	ctx := &chainorContext{
-		ctx:       context.Background(),
+		engine:    f.opt.engine,
 		f:         f,
 		keyValues: cmp.New[any](),
+		results:   cmp.New[[]any](),
+		hops:      new(int32),
+	}
+	if len(f.opt.values) > 0 {
+		ctx.keyValues.MSet(f.opt.values)
 	}
 
+	// 派生自 Engine 的 context，Shutdown 到期时可统一取消
 	if f.opt.timeout != time.Duration(0) {
-		ctx.ctx, ctx.cancel = context.WithTimeout(context.Background(), f.opt.timeout)
+		ctx.ctx, ctx.cancel = context.WithTimeout(ctx.engine.ctx, f.opt.timeout)
+	} else {
+		ctx.ctx, ctx.cancel = context.WithCancel(ctx.engine.ctx)
 	}
 	return ctx
 }
 
+// finish 链路结束，一次 Invoke 仅生效一次
+func (c *chainorContext) finish() {
+	c.finishOnce.Do(func() {
+		c.cancel()
+		c.engine.release()
+	})
+}
+
+// isolate 派生隔离分支的上下文
+//
+// 分支首次读取父上下文的值时深拷贝一份到分支内，之后分支内的读写、删除均不影响父上下文；
+// 分支有独立的 cancel，结束后需调用 cancel 释放
+func (c *chainorContext) isolate(f *future) *chainorContext {
+	ctx := c.derive(f, c.results)
+	ctx.deep = true
+	ctx.promotes = cmp.New[struct{}]()
+	return ctx
+}
+
+// branch 派生 Parallel 分支的上下文
+//
+// 分支内的写入、删除及具名节点的结果只在分支内可见，汇合时由 commit 回写到父上下文，未回写的分支全部丢弃；
+// 分支有独立的 cancel，汇合后需调用 cancel 取消仍在执行的分支
+func (c *chainorContext) branch(f *future) *chainorContext {
+	return c.derive(f, cmp.New[[]any]())
+}
+
+func (c *chainorContext) derive(f *future, results cmp.ConcurrentMap[[]any]) *chainorContext {
+	ctx := &chainorContext{
+		engine:    c.engine,
+		f:         f,
+		keyValues: cmp.New[any](),
+		results:   results,
+		hops:      c.hops,
+		parent:    c,
+		dirty:     cmp.New[struct{}](),
+	}
+	ctx.ctx, ctx.cancel = context.WithCancel(c.ctx)
+	return ctx
+}
+
+// promote 将标记的 key 回写到父上下文，分支内已删除的 key 在父上下文中同样删除
+func (c *chainorContext) promote() {
+	if c.parent == nil {
+		return
+	}
+	c.writeBack(c.promotes.Keys())
+}
+
+// commit 将分支内写入、删除过的 key 及具名节点的结果回写到父上下文
+func (c *chainorContext) commit() {
+	if c.parent == nil {
+		return
+	}
+	c.writeBack(c.dirty.Keys())
+
+	for name, res := range c.results.Items() {
+		c.parent.results.Set(name, res)
+	}
+}
+
+func (c *chainorContext) writeBack(keys []string) {
+	for _, key := range keys {
+		if v, ok := c.value(key); ok {
+			c.parent.set(key, v)
+		} else {
+			c.parent.remove(key)
+		}
+	}
+}
+
+// value 读取 key 对应的值，隔离分支内首次读取父上下文的值时深拷贝到分支内
+func (c *chainorContext) value(key string) (any, bool) {
+	if v, ok := c.keyValues.Get(key); ok {
+		switch v.(type) {
+		case absent, deleted:
+			return nil, false
+		}
+		return v, true
+	}
+	if c.parent == nil {
+		return nil, false
+	}
+	if !c.deep {
+		return c.parent.value(key)
+	}
+	return c.local(key)
+}
+
+// local 将父上下文的值放入当前上下文，隔离分支深拷贝，已存在时以当前上下文的值为准
+func (c *chainorContext) local(key string) (any, bool) {
+	if _, ok := c.keyValues.Get(key); ok || c.parent == nil {
+		return c.value(key)
+	}
+
+	v, ok := c.parent.value(key)
+	if !ok {
+		return nil, false
+	}
+	if c.deep {
+		v = clone.Clone(v)
+	}
+	// 并行任务已先行拷贝或写入时以分支内的值为准
+	c.keyValues.SetIfAbsent(key, v)
+	return c.value(key)
+}
+
+func (c *chainorContext) set(key string, value any) {
+	c.keyValues.Set(key, value)
+	c.touch(key)
+}
+
+func (c *chainorContext) remove(key string) {
+	if c.parent == nil {
+		c.keyValues.Remove(key)
+		return
+	}
+	// 分支内以删除标记遮蔽父上下文的值
+	c.keyValues.Set(key, deleted{})
+	c.touch(key)
+}
+
+// touch 标记分支内写入、删除过的 key
+func (c *chainorContext) touch(key string) {
+	if c.parent != nil {
+		c.dirty.Set(key, struct{}{})
+	}
+}
+
+func (c *chainorContext) result(name string) ([]any, bool) {
+	if res, ok := c.results.Get(name); ok || c.parent == nil {
+		return res, ok
+	}
+	return c.parent.result(name)
+}
+
+func (c *chainorContext) allResults() map[string][]any {
+	if c.parent == nil {
+		return c.results.Items()
+	}
+
+	all := c.parent.allResults()
+	for name, res := range c.results.Items() {
+		all[name] = res
+	}
+	return all
+}
+
+// snapshot 上下文值的只读快照
+func (c *chainorContext) snapshot() Values {
+	items := c.keyValues.Items()
+	for k, v := range items {
+		switch v.(type) {
+		case absent, deleted:
+			delete(items, k)
+		}
+	}
+	return Values{
+		m: items,
+	}
+}
+
+// err 链路被中断的原因
+func (c *chainorContext) err() error {
+	if c.engine.ctx.Err() != nil {
+		return ErrShutdown
+	}
+	return ErrTimeout
+}
+
 func (s *step) newTaskContext() *TaskContext {
 	return &TaskContext{
 		s: s,
@@ -50,27 +247,60 @@ func (c *TaskContext) Cancel() context.CancelFunc {
 
 // WithValue 全局有效，且支持并行操作
 func (c *TaskContext) WithValue(key string, value any) {
-	c.s.f.ctx.keyValues.Set(key, value)
+	c.s.f.ctx.set(key, value)
 }
 
 // Value 获取 key 对应的 value
 func (c *TaskContext) Value(key string) any {
-	if v, ok := c.s.f.ctx.keyValues.Get(key); ok {
-		return v
+	v, _ := c.s.f.ctx.value(key)
+	return v
+}
+
+// Finish 提前结束链路，以 result 作为成功回调的结果，后续节点（包括外层链路的节点）不再执行
+//
+// 用法为在任务函数内 return nil, ctx.Finish(result...)；直接返回 ErrStop 时以该任务的 lastResult 作为结果
+func (c *TaskContext) Finish(result ...any) error {
+	return &stopError{
+		result: result,
+	}
+}
+
+// Promote 在隔离分支内（见 Switch2 的 Isolate）标记 key，分支成功结束时将其当前值回写到父上下文，非隔离分支内调用无效果
+//
+// Parallel 分支内调用时标记到外层最近的隔离分支
+func (c *TaskContext) Promote(keys ...string) {
+	ctx := c.s.f.ctx
+	for ctx != nil && !ctx.deep {
+		ctx = ctx.parent
 	}
-	return nil
+	if ctx == nil {
+		return
+	}
+	for _, key := range keys {
+		ctx.promotes.Set(key, struct{}{})
+	}
+}
+
+// ResultOf 获取本次调用中已执行的具名节点（见 WithTaskName、NextN）的结果，同名节点以最后执行的为准
+func (c *TaskContext) ResultOf(name string) ([]any, bool) {
+	return c.s.f.ctx.result(name)
+}
+
+// Results 本次调用中已执行的所有具名节点的结果
+func (c *TaskContext) Results() map[string][]any {
+	return c.s.f.ctx.allResults()
 }
 
 func (c *TaskContext) ChainorName() string {
-	return c.s.f.c.opt.name
+	return c.s.p.opt.name
 }
 
 func (c *TaskContext) ChainorParam() any {
-	return c.s.f.c.opt.param
+	return c.s.p.opt.param
 }
 
 func (c *TaskContext) ChainorProps() M {
-	return c.s.f.c.opt.props
+	return c.s.p.opt.props
 }
 
 func (c *TaskContext) TaskParam() any {
@@ -81,6 +311,23 @@ func (c *TaskContext) TaskProps() M {
 	return c.s.n.opt.props
 }
 
+// TaskName 节点名称，见 WithTaskName
+func (c *TaskContext) TaskName() string {
+	return c.s.n.opt.name
+}
+
+func (c *TaskContext) nodeName() string {
+	if name := c.TaskName(); name != "" {
+		return fmt.Sprintf("%q", name)
+	}
+	return "<unnamed>"
+}
+
+// TaskVersion NextN 注册的节点任务在构建时的任务名版本号，其他节点为 0
+func (c *TaskContext) TaskVersion() uint64 {
+	return c.s.n.opt.version
+}
+
 func (c *TaskContext) Param() any {
 	return c.s.f.opt.param
 }
@@ -88,7 +335,3 @@ func (c *TaskContext) Param() any {
 func (c *TaskContext) Props() M {
 	return c.s.f.opt.props
 }
-
-func (c *TaskContext) future() *future {
-	return c.s.f
-}
@@ -0,0 +1,113 @@
+package chainor
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// Engine 负责跟踪 Invoke 触发的链路，用于优雅退出
+	Engine struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		mu      sync.Mutex
+		closed  bool
+		running sync.WaitGroup
+
+		// pools 具名协程池，Shutdown 时释放
+		pools *poolBucket
+		// nodePools 经该 Engine 执行过的链路的节点协程池（WithWorkerPool），Shutdown 时释放
+		nodePools map[*nodePools]struct{}
+	}
+)
+
+var defaultEngine = NewEngine()
+
+// NewEngine 返回一个 Engine 实例，通过 WithEngine 指定 Invoke 所属的 Engine，未指定时使用全局 Engine
+func NewEngine() *Engine {
+	e := &Engine{
+		pools:     newPoolBucket(),
+		nodePools: make(map[*nodePools]struct{}),
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	return e
+}
+
+// acquire 登记一次执行中的链路及其节点协程池，Engine 已关闭时返回 false
+func (e *Engine) acquire(p *Plan) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return false
+	}
+	e.running.Add(1)
+	e.nodePools[p.pools] = struct{}{}
+	return true
+}
+
+func (e *Engine) release() {
+	e.running.Done()
+}
+
+// RegisterPool 注册具名协程池，通过 WithWorkerPoolN 引用，仅对指定该 Engine 的 Invoke 可见（见 WithEngine）
+//
+// 多条链路、多个节点可共享同一个具名协程池，从而共享同一份并发额度；同名协程池已存在时返回 ErrPoolExists，Engine 已关闭时返回 ErrShutdown
+func (e *Engine) RegisterPool(name string, size int, withFunc ...PoolOption) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return ErrShutdown
+	}
+	return e.pools.register(name, size, withFunc...)
+}
+
+// ReleasePool 释放具名协程池，释放后引用该协程池的节点任务将返回 ErrPoolNotFound，之后可重新注册同名协程池
+func (e *Engine) ReleasePool(name string) {
+	e.pools.release(name)
+}
+
+// Shutdown 停止接收新的 Invoke（之后的 Invoke 以 ErrShutdown 失败），并等待执行中的链路全部结束，
+// 之后释放该 Engine 的具名协程池，以及经该 Engine 执行过的链路的节点协程池（WithWorkerPool）
+//
+// ctx 到期时仍未结束的链路会被取消，其失败回调收到 ErrShutdown，此时返回 ctx.Err()
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	defer e.releasePools()
+
+	idle := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		e.cancel()
+		return ctx.Err()
+	}
+}
+
+func (e *Engine) releasePools() {
+	e.pools.releaseAll()
+
+	e.mu.Lock()
+	pools := e.nodePools
+	e.nodePools = make(map[*nodePools]struct{})
+	e.mu.Unlock()
+
+	for np := range pools {
+		np.release()
+	}
+}
+
+// Shutdown 关闭全局 Engine，详见 Engine.Shutdown，不影响其他 Engine
+func Shutdown(ctx context.Context) error {
+	return defaultEngine.Shutdown(ctx)
+}
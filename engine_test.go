@@ -0,0 +1,128 @@
+package chainor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShutdown(t *testing.T) {
+	Convey("Shutdown waits for in-flight invocations", t, func(c C) {
+		e := NewEngine()
+		finished := false
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(200 * time.Millisecond)
+				return 1, nil
+			})
+
+		Invoke(chn, func(result []any) {
+			finished = true
+		}, nil, WithEngine(e))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		So(e.Shutdown(ctx), ShouldBeNil)
+		So(finished, ShouldBeTrue)
+
+		Convey("Invoke after shutdown", func(c C) {
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+
+			Invoke(chn, nil, func(err error) {
+				c.So(err, ShouldEqual, ErrShutdown)
+				wg.Done()
+			}, WithEngine(e))
+
+			wg.Wait()
+		})
+	})
+
+	Convey("Shutdown cancels at the deadline", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		e := NewEngine()
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(2 * time.Second)
+				return 2, nil
+			})
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrShutdown)
+			wg.Done()
+		}, WithEngine(e), WithTimeout(5*time.Second))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		So(errors.Is(e.Shutdown(ctx), context.DeadlineExceeded), ShouldBeTrue)
+		wg.Wait()
+	})
+
+	Convey("Shutdown releases named pools of the engine", t, func(c C) {
+		e1, e2 := NewEngine(), NewEngine()
+		defer e2.Shutdown(context.Background())
+
+		So(e1.RegisterPool("wp", 2), ShouldBeNil)
+		So(e2.RegisterPool("wp", 2), ShouldBeNil)
+
+		wp1, _ := e1.pools.get("wp")
+		wp2, _ := e2.pools.get("wp")
+
+		So(e1.Shutdown(context.Background()), ShouldBeNil)
+		So(wp1.IsClosed(), ShouldBeTrue)
+		So(e1.RegisterPool("wp", 2), ShouldEqual, ErrShutdown)
+
+		// 其他 Engine 的同名协程池不受影响
+		So(wp2.IsClosed(), ShouldBeFalse)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}, WithWorkerPoolN("wp"))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{1})
+			wg.Done()
+		}, nil, WithEngine(e2))
+
+		wg.Wait()
+	})
+
+	Convey("Shutdown releases node pools of invoked chains", t, func(c C) {
+		e := NewEngine()
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}, WithWorkerPool(2))
+
+		Invoke(chn, func(result []any) {
+			wg.Done()
+		}, nil, WithEngine(e))
+
+		wg.Wait()
+
+		wp := chn.pools.pools[0]
+		So(wp.IsClosed(), ShouldBeFalse)
+		So(e.Shutdown(context.Background()), ShouldBeNil)
+		So(wp.IsClosed(), ShouldBeTrue)
+	})
+}
@@ -1,6 +1,8 @@
 package chainor
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/zeromicro/go-zero/core/threading"
@@ -9,11 +11,13 @@ import (
 type (
 	future struct {
 		opt *option
-		c   *Chainor
+		p   *Plan
 		ctx *chainorContext
 
-		onSuccess func([]any)
-		onFailed  func(error)
+		onSuccess func([]any, Values)
+		onFailed  func(error, Values)
+		// snapshot 回调时是否需要上下文值的快照
+		snapshot bool
 	}
 
 	resChan struct {
@@ -34,6 +38,7 @@ type (
 
 	step struct {
 		n   *node
+		p   *Plan
 		f   *future
 		ctx *TaskContext
 
@@ -47,12 +52,38 @@ type (
 		err error
 		msg any
 	}
+
+	// stopError 提前结束链路，result 为成功回调的结果
+	stopError struct {
+		result []any
+	}
 )
 
-func (c *Chainor) newFuture(opt *option, onSuccess func([]any), onFailed func(error)) *future {
+func (e *stopError) Error() string {
+	return ErrStop.Error()
+}
+
+func (e *stopError) Unwrap() error {
+	return ErrStop
+}
+
+// asStop err 是否为提前结束，直接返回的 ErrStop 以 lastRes 作为结果
+func asStop(err error, lastRes []any) (*stopError, bool) {
+	if !errors.Is(err, ErrStop) {
+		return nil, false
+	}
+
+	var stop *stopError
+	if !errors.As(err, &stop) {
+		stop = &stopError{result: lastRes}
+	}
+	return stop, true
+}
+
+func (p *Plan) newFuture(opt *option, onSuccess func([]any, Values), onFailed func(error, Values)) *future {
 	f := &future{
 		opt:       opt,
-		c:         c,
+		p:         p,
 		onSuccess: onSuccess,
 		onFailed:  onFailed,
 	}
@@ -61,48 +92,90 @@ func (c *Chainor) newFuture(opt *option, onSuccess func([]any), onFailed func(er
 	return f
 }
 
-func (f *future) clone() *future {
-	nf := *f
-	return &nf
-}
-
 func (f *future) forward(lastRes ...any) {
 	threading.GoSafe(func() {
-		done := true
-
-		iter := f.c.queue.Iterator()
-		for iter.HasNext() {
-			n := iter.Next().(*node)
-
-			if res, err := (&step{
-				n:       n,
-				f:       f,
-				lastRes: lastRes,
-			}).start(); err == nil && !n.opt.skipResult {
-				lastRes = res
-			} else {
-				if err == errNotDone {
-					done = false
-				} else if f.onFailed != nil {
-					f.onFailed(err)
-				}
-				goto failed
+		defer f.ctx.finish()
+
+		res, err := f.exec(f.p, lastRes)
+		if stop, ok := asStop(err, nil); ok {
+			res, err = stop.result, nil
+		}
+		// 逐层向外均未找到跳转目标
+		var g *gotoError
+		if errors.As(err, &g) {
+			err = fmt.Errorf("%w: %s", ErrLabelNotFound, g.label)
+		}
+		if err != nil {
+			if f.onFailed != nil {
+				f.onFailed(err, f.values())
 			}
+			return
 		}
 		if f.onSuccess != nil {
-			f.onSuccess(lastRes)
+			f.onSuccess(res, f.values())
 		}
+	})
+}
+
+func (f *future) values() Values {
+	if !f.snapshot {
+		return Values{}
+	}
+	return f.ctx.snapshot()
+}
 
-	failed:
-		if done && f.ctx.cancel != nil {
-			f.ctx.cancel()
+// isolate 派生使用隔离上下文的 future，仅用于执行分支链路，不触发回调
+func (f *future) isolate() *future {
+	nf := &future{
+		opt: f.opt,
+		p:   f.p,
+	}
+	nf.ctx = f.ctx.isolate(nf)
+	return nf
+}
+
+// branch 派生执行 Parallel 分支的 future，仅用于执行分支链路，不触发回调
+func (f *future) branch() *future {
+	nf := &future{
+		opt: f.opt,
+		p:   f.p,
+	}
+	nf.ctx = f.ctx.branch(nf)
+	return nf
+}
+
+// exec 在当前协程内依次执行 p 的节点，Switch、Switch2 等节点的分支链路也通过 exec 执行
+//
+// 跳转的目标在 p 内时从目标节点继续执行，否则返回给外层链路
+func (f *future) exec(p *Plan, lastRes []any) ([]any, error) {
+	for i := 0; i < len(p.nodes); i++ {
+		n := p.nodes[i]
+		res, err := (&step{
+			n:       n,
+			p:       p,
+			f:       f,
+			lastRes: lastRes,
+		}).start()
+		if err != nil {
+			to, res, err := f.jump(p, err)
+			if err != nil {
+				return nil, err
+			}
+			i, lastRes = to-1, res
+			continue
 		}
-	})
+		if !n.opt.skipResult {
+			lastRes = res
+		}
+	}
+	return lastRes, nil
 }
 
-func newResChan() *resChan {
+// newResChan size 为预期的回应数，缓冲足够时回应方不会阻塞，
+// 避免协程池容量小于任务数或提交失败时，在 wait 之前回应导致死锁
+func newResChan(size int) *resChan {
 	c := &resChan{
-		c:         make(chan *result),
+		c:         make(chan *result, size),
 		stopChan:  make(chan struct{}),
 		closeOnce: new(sync.Once),
 		stopOnce:  new(sync.Once),
@@ -214,7 +287,18 @@ func (s *step) rangeCalls(callback func(taskFunc TaskFunc)) {
 }
 
 func (s *step) call(call TaskFunc) {
+	// panic 时回应错误，避免节点一直等待
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(s.p.logger(), r)
+			s.resChan.nack(fmt.Errorf("%w: %v", ErrPanic, r))
+		}
+	}()
+
 	if res, err := call(s.newTaskContext(), s.lastRes); err != nil {
+		if stop, ok := asStop(err, s.lastRes); ok {
+			err = stop
+		}
 		s.resChan.nack(err)
 	} else {
 		s.resChan.ack(res)
@@ -222,12 +306,31 @@ func (s *step) call(call TaskFunc) {
 }
 
 func (s *step) runWithWorkerPool() {
-	s.rangeCalls(func(call TaskFunc) {
-		if err := s.n.workerpool.Submit(func() {
-			s.call(call)
-		}); err != nil {
-			s.resChan.nack(err)
+	wp := s.n.workerpool
+	if s.n.opt.poolName != "" {
+		var ok bool
+		if wp, ok = s.f.ctx.engine.pools.get(s.n.opt.poolName); !ok {
+			s.rangeCalls(func(_ TaskFunc) {
+				s.resChan.nack(ErrPoolNotFound)
+			})
+			return
 		}
+	}
+
+	// 协程池已满时 Submit 会阻塞，在单独的协程内提交，wait 仍可响应超时、Shutdown
+	threading.GoSafe(func() {
+		s.rangeCalls(func(call TaskFunc) {
+			if err := wp.Submit(func() {
+				// 等待期间链路已被中断时不再执行
+				if s.f.ctx.ctx.Err() != nil {
+					s.resChan.nack(s.f.ctx.err())
+					return
+				}
+				s.call(call)
+			}); err != nil {
+				s.resChan.nack(err)
+			}
+		})
 	})
 }
 
@@ -250,8 +353,8 @@ func (s *step) runCalls() {
 }
 
 func (s *step) init() {
-	s.resChan = newResChan()
 	s.newReutrnRes()
+	s.resChan = newResChan(s.nowRes.maxCnt)
 
 	s.resChan.wait()
 }
@@ -267,6 +370,12 @@ func (s *step) decide(res *result) bool {
 	}
 	s.nowRes.inc()
 
+	// 提前结束、跳转不受 WithAnyPassed 影响
+	if isControl(res.err) {
+		s.nowRes.withError(res.err)
+		return done()
+	}
+
 	// 期望的结果是否已收到
 	switch {
 	case s.n.opt.anyPassedFunc != nil:
@@ -292,11 +401,13 @@ func (s *step) decide(res *result) bool {
 }
 
 func (s *step) wait() ([]any, error) {
+	interrupted := false
 FOR:
 	for {
 		select {
 		case <-s.f.ctx.ctx.Done():
-			s.nowRes.withError(ErrTimeout)
+			interrupted = true
+			s.nowRes.withError(s.f.ctx.err())
 			s.resChan.stop()
 			break FOR
 		case value, ok := <-s.resChan.c:
@@ -310,7 +421,7 @@ FOR:
 	}
 
 	// 等待 close，防止泄露，此处不会持久阻塞，close 会立马到来
-	if s.nowRes.error() == ErrTimeout {
+	if interrupted {
 		for range s.resChan.c {
 		}
 	}
@@ -331,12 +442,41 @@ func (s *step) run() ([]any, error) {
 	return s.wait()
 }
 
-func (s *step) start() ([]any, error) {
-	if s.n.opt.skippedFunc != nil {
+func (s *step) start() (res []any, err error) {
+	// WithSkipped、Predicate、循环条件、WithResultMapper 等回调在当前协程执行，panic 时以 ErrPanic 失败
+	defer func() {
+		if r := recover(); r != nil {
+			logPanic(s.p.logger(), r)
+			res, err = nil, fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+
+	// 延迟绑定的节点由各分组子节点判定跳过、转换结果及记录结果，与 NextN 按分组生效一致
+	lazy := s.n.opt.lazy
+
+	if s.n.opt.skippedFunc != nil && !lazy {
 		if s.n.opt.skippedFunc(s.lastRes) {
 			return s.lastRes, nil
 		}
 	}
 
-	return s.run()
+	if s.n.flow != nil {
+		res, err = s.n.flow(s)
+	} else {
+		res, err = s.run()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lazy {
+		return res, nil
+	}
+	if s.n.opt.mapper != nil {
+		res = s.n.opt.mapper(res)
+	}
+	if s.n.opt.name != "" {
+		s.f.ctx.results.Set(s.n.opt.name, res)
+	}
+	return res, nil
 }
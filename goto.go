@@ -0,0 +1,79 @@
+package chainor
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxHops 一次 Invoke 默认的最大跳转次数，见 WithMaxHops
+	DefaultMaxHops = 100
+)
+
+// gotoError 跳转到 label 标记的节点，result 为目标节点的 lastResult
+type gotoError struct {
+	label  string
+	result []any
+}
+
+func (e *gotoError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrLabelNotFound.Error(), e.label)
+}
+
+// Goto 跳转到 WithLabel 标记的节点继续执行，以 result 作为该节点的 lastResult，result 为空时为当前任务的 lastResult
+//
+// 用法为在任务函数内 return nil, ctx.Goto(label, result...)，SwitchCtx、Switch2Ctx 的 predicate 内同样可以返回
+// 目标节点从当前链路开始逐层向外查找，例如 Switch2 分支、循环体内可以跳转到外层链路的节点，反之则不行，找不到时返回 ErrLabelNotFound
+// 每次 Invoke 的跳转次数超过 WithMaxHops（默认 DefaultMaxHops）时返回 ErrMaxHops
+func (c *TaskContext) Goto(label string, result ...any) error {
+	if len(result) == 0 {
+		result = c.s.lastRes
+	}
+	return &gotoError{
+		label:  label,
+		result: result,
+	}
+}
+
+// labels 校验并收集 nodes 上的标签，同一条链路内标签不可重复
+func labels(nodes []*node) (map[string]int, error) {
+	m := make(map[string]int)
+	for i, n := range nodes {
+		if n.opt.label == "" {
+			continue
+		}
+		if _, ok := m[n.opt.label]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrLabelDuplicated, n.opt.label)
+		}
+		m[n.opt.label] = i
+	}
+	return m, nil
+}
+
+// jump err 为跳转且目标标签在 p 内时，返回目标节点的下标及 lastResult
+func (f *future) jump(p *Plan, err error) (int, []any, error) {
+	var g *gotoError
+	if !errors.As(err, &g) {
+		return 0, nil, err
+	}
+	i, ok := p.labels[g.label]
+	if !ok {
+		return 0, nil, err
+	}
+
+	limit := f.opt.maxHops
+	if limit <= 0 {
+		limit = DefaultMaxHops
+	}
+	if atomic.AddInt32(f.ctx.hops, 1) > int32(limit) {
+		return 0, nil, ErrMaxHops
+	}
+	return i, g.result, nil
+}
+
+// isControl err 是否为 ErrStop、Goto 等控制流转，而非任务失败
+func isControl(err error) bool {
+	var g *gotoError
+	return errors.Is(err, ErrStop) || errors.As(err, &g)
+}
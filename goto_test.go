@@ -0,0 +1,68 @@
+package chainor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGoto(t *testing.T) {
+	Convey("Goto labelled nodes", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.Param(), nil
+		}).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0], nil
+		}, WithLabel("validate")).Switch2Ctx(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			if lastResult[0].(int) < 0 {
+				return nil, ctx.Goto("fail")
+			}
+			return lastResult[0].(int) >= 3, nil
+		}).Case(true, func(c1 *Chainor) {
+			c1.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "valid", nil
+			})
+		}).Default(func(c2 *Chainor) {
+			c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				// fix 后重新 validate
+				return nil, ctx.Goto("validate", lastResult[0].(int)+1)
+			})
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{"valid"})
+			wg.Done()
+		}, nil, WithParam(0))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrMaxHops)
+			wg.Done()
+		}, WithParam(0), WithMaxHops(2))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(errors.Is(err, ErrLabelNotFound), ShouldBeTrue)
+			wg.Done()
+		}, WithParam(-1))
+
+		wg.Wait()
+	})
+
+	Convey("Duplicated labels", t, func() {
+		task := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, nil
+		}
+
+		_, err := NewChainor().Next(task, WithLabel("a")).Next(task, WithLabel("a")).Build()
+		So(errors.Is(err, ErrLabelDuplicated), ShouldBeTrue)
+
+		_, err = NewChainor().Next(task, WithLabel("a")).Repeat(1, func(c *Chainor) {
+			c.Next(task, WithLabel("a"))
+		}).Build()
+		So(err, ShouldBeNil)
+	})
+}
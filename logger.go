@@ -0,0 +1,78 @@
+package chainor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+type (
+	// Logger 日志接口，方法签名与 *slog.Logger 一致，可直接传入 slog.Default()
+	//
+	// args 为交替排列的 key、value
+	Logger interface {
+		Debug(msg string, args ...any)
+		Info(msg string, args ...any)
+		Warn(msg string, args ...any)
+		Error(msg string, args ...any)
+	}
+
+	// stdLogger 基于标准库 log 的默认实现
+	stdLogger struct{}
+)
+
+var (
+	loggerRW      sync.RWMutex
+	defaultLogger Logger = stdLogger{}
+)
+
+// SetLogger 设置全局 Logger，未通过 WithChainorLogger 指定 Logger 的 Chainor 均使用该 Logger
+func SetLogger(logger Logger) {
+	if logger == nil {
+		logger = stdLogger{}
+	}
+
+	loggerRW.Lock()
+	defer loggerRW.Unlock()
+	defaultLogger = logger
+}
+
+func globalLogger() Logger {
+	loggerRW.RLock()
+	defer loggerRW.RUnlock()
+	return defaultLogger
+}
+
+func (l stdLogger) output(level, msg string, args []any) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+
+	for i := 0; i < len(args); i += 2 {
+		b.WriteByte(' ')
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, "%v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, "%v", args[i])
+		}
+	}
+	log.Print(b.String())
+}
+
+func (l stdLogger) Debug(msg string, args ...any) {
+	l.output("DEBUG", msg, args)
+}
+
+func (l stdLogger) Info(msg string, args ...any) {
+	l.output("INFO", msg, args)
+}
+
+func (l stdLogger) Warn(msg string, args ...any) {
+	l.output("WARN", msg, args)
+}
+
+func (l stdLogger) Error(msg string, args ...any) {
+	l.output("ERROR", msg, args)
+}
@@ -0,0 +1,92 @@
+package chainor
+
+const (
+	// DefaultMaxIterations While、Until 默认的最大迭代次数，见 WithMaxIterations
+	DefaultMaxIterations = 1000
+)
+
+type (
+	// Condition 循环条件，lastResult 为上一次迭代的结果，首次迭代前为循环节点的 lastResult
+	Condition func(lastResult []any) bool
+
+	loop struct {
+		// before 为 true 时先判定再执行（While），否则先执行再判定（Until）
+		before bool
+		cond   Condition
+		// fixed 为 true 时固定迭代 times 次（Repeat），不判定 cond
+		fixed bool
+		times int
+		body  *Plan
+	}
+)
+
+// While 循环节点，cond 为 true 时执行一次 body 注册的子链路，每次迭代的结果作为下一次迭代及 cond 的 lastResult
+//
+// cond 首次即为 false 时该节点透传 lastResult
+// 迭代次数超过 WithMaxIterations（默认 DefaultMaxIterations）时返回 ErrMaxIterations
+// withFunc 作用于整个循环节点，例如 WithTaskName、WithSkipped、WithResultMapper
+func (c *Chainor) While(cond Condition, body Case, withFunc ...TaskOption) *Chainor {
+	return c.nextLoop(&loop{
+		before: true,
+		cond:   cond,
+	}, body, withFunc...)
+}
+
+// Until 循环节点，先执行一次 body 注册的子链路，直到 cond 为 true，其余同 While
+func (c *Chainor) Until(cond Condition, body Case, withFunc ...TaskOption) *Chainor {
+	return c.nextLoop(&loop{
+		cond: cond,
+	}, body, withFunc...)
+}
+
+// Repeat 循环节点，执行 n 次 body 注册的子链路，n 不大于 0 时该节点透传 lastResult，不受 WithMaxIterations 限制
+func (c *Chainor) Repeat(n int, body Case, withFunc ...TaskOption) *Chainor {
+	return c.nextLoop(&loop{
+		fixed: true,
+		times: n,
+	}, body, withFunc...)
+}
+
+func (c *Chainor) nextLoop(l *loop, body Case, withFunc ...TaskOption) *Chainor {
+	l.body = c.compile(body)
+	return c.nextFlow(l.flow, withFunc...)
+}
+
+func (l *loop) flow(st *step) ([]any, error) {
+	limit := st.n.opt.maxIterations
+	if limit <= 0 {
+		limit = DefaultMaxIterations
+	}
+
+	lastRes := st.lastRes
+	for i := 0; ; i++ {
+		if l.fixed {
+			if i >= l.times {
+				break
+			}
+		} else {
+			if l.before && !l.cond(lastRes) {
+				break
+			}
+			if i >= limit {
+				return nil, ErrMaxIterations
+			}
+		}
+
+		// 迭代之间响应超时、Shutdown
+		if st.f.ctx.ctx.Err() != nil {
+			return nil, st.f.ctx.err()
+		}
+
+		res, err := st.f.exec(l.body, lastRes)
+		if err != nil {
+			return nil, err
+		}
+		lastRes = res
+
+		if !l.fixed && !l.before && l.cond(lastRes) {
+			break
+		}
+	}
+	return lastRes, nil
+}
@@ -0,0 +1,101 @@
+package chainor
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoop(t *testing.T) {
+	incr := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+		return lastResult[0].(int) + 1, nil
+	}
+	lessThan := func(n int) Condition {
+		return func(lastResult []any) bool {
+			return lastResult[0].(int) < n
+		}
+	}
+	start := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+		return ctx.Param(), nil
+	}
+
+	Convey("While", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(3)
+
+		chn := NewChainor().Next(start).While(lessThan(5), func(c1 *Chainor) {
+			c1.Next(incr).Next(incr)
+		}, WithTaskName("loop"), WithMaxIterations(3))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{6})
+			wg.Done()
+		}, nil, WithParam(0))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{10})
+			wg.Done()
+		}, nil, WithParam(10))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrMaxIterations)
+			wg.Done()
+		}, WithParam(-10))
+
+		wg.Wait()
+	})
+
+	Convey("Until", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().Next(start).Until(func(lastResult []any) bool {
+			return lastResult[0].(int) >= 5
+		}, func(c1 *Chainor) {
+			c1.Next(incr)
+		}).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0].(int) * 10, nil
+		})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{110})
+			wg.Done()
+		}, nil, WithParam(10))
+
+		wg.Wait()
+	})
+
+	Convey("Repeat", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		Invoke(NewChainor().Next(start).Repeat(3, func(c1 *Chainor) {
+			c1.Next(incr).Switch(func(lastResult []any) (result any) {
+				return lastResult[0].(int) % 2
+			}).Case(0, incr).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0], nil
+			})
+		}), func(result []any) {
+			// 0 -> 1 -> 3 -> 5
+			c.So(result, ShouldResemble, []any{5})
+			wg.Done()
+		}, nil, WithParam(0))
+
+		Invoke(NewChainor().Next(start).Repeat(0, func(c1 *Chainor) {
+			c1.Next(incr)
+		}), func(result []any) {
+			c.So(result, ShouldResemble, []any{7})
+			wg.Done()
+		}, nil, WithParam(7))
+
+		wg.Wait()
+	})
+
+	Convey("Loop body with unknown name", t, func() {
+		_, err := NewChainor().Repeat(1, func(c1 *Chainor) {
+			c1.NextN("loop-unknown")
+		}).Build()
+		So(err, ShouldNotBeNil)
+	})
+}
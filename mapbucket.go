@@ -10,6 +10,8 @@ import (
 type (
 	mapBucket interface {
 		put(bkey any, bvalue any)
+		replace(bkey any, bvalues ...any)
+		remove(bkey any)
 		exist(bkey any) bool
 		eachKey(func(bkey any))
 		iteratorB(bkey any) iterator
@@ -51,12 +53,33 @@ func (m *syncMapBucket) put(bkey any, bvalue any) {
 	}
 }
 
+// replace 以 bvalues 整体替换 bkey 下的所有值，已返回的迭代器不受影响
+func (m *syncMapBucket) replace(bkey any, bvalues ...any) {
+	q := queue.DefaultQueue()
+	for _, v := range bvalues {
+		q.Offer(v)
+	}
+
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	m.mp[bkey] = q
+}
+
+// remove 删除 bkey 下的所有值，已返回的迭代器不受影响
+func (m *syncMapBucket) remove(bkey any) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	delete(m.mp, bkey)
+}
+
 func (m *syncMapBucket) iteratorB(bkey any) iterator {
 	m.rw.RLock()
 	defer m.rw.RUnlock()
 
 	if v, ok := m.mp[bkey]; ok {
-		// mapBucket 没有 remove 操作，且 queue 为无锁队列，故 return 后的操作不会有并发问题
+		// replace、remove 均不修改已有的 queue，且 queue 为无锁队列，故 return 后的操作不会有并发问题
 		return v.(queue.Queue).Iterator()
 	}
 	return queue.DefaultQueue().Iterator()
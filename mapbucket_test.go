@@ -39,3 +39,28 @@ func TestPutAndGet(t *testing.T) {
 		So(vmap, ShouldResemble, map[string]bool{"1": true, "2": true})
 	})
 }
+
+func TestReplaceAndRemove(t *testing.T) {
+	Convey("Replace and remove mapbucket", t, func() {
+		mp := newSyncMapBucket()
+
+		mp.put("1", "a")
+		mp.put("1", "b")
+		iter := mp.iteratorB("1")
+
+		mp.replace("1", "c")
+		So(mp.lenB("1"), ShouldEqual, 1)
+		So(mp.iteratorB("1").Next(), ShouldEqual, "c")
+
+		mp.remove("1")
+		So(mp.exist("1"), ShouldBeFalse)
+		So(mp.lenB("1"), ShouldEqual, 0)
+
+		// 已返回的迭代器不受影响
+		varray := make([]string, 0, 2)
+		for iter.HasNext() {
+			varray = append(varray, iter.Next().(string))
+		}
+		So(varray, ShouldResemble, []string{"a", "b"})
+	})
+}
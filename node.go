@@ -1,7 +1,7 @@
 package chainor
 
 import (
-	"log"
+	"fmt"
 	"runtime/debug"
 
 	"github.com/panjf2000/ants/v2"
@@ -17,13 +17,22 @@ type (
 		calls      TaskFuncs
 		workerpool *ants.Pool
 
+		// flow 不为空时，节点不执行 calls，而是由 flow 调度，例如 Switch、Switch2
+		flow flowFunc
+
 		priority int
 	}
+
+	flowFunc func(s *step) ([]any, error)
 )
 
-func (n *node) panicHandler() func(interface{}) {
+func logPanic(logger Logger, err interface{}) {
+	logger.Error("executor coroutine panic", "err", err, "stack", string(debug.Stack()))
+}
+
+func (n *node) panicHandler(logger Logger) func(interface{}) {
 	return func(err interface{}) {
-		log.Printf("executor coroutine panic: %v\n%v", err, string(debug.Stack()))
+		logPanic(logger, err)
 		n.clean()
 	}
 }
@@ -31,17 +40,25 @@ func (n *node) panicHandler() func(interface{}) {
 func (n *node) clean() {
 }
 
-func (n *node) concrete() *node {
+// concrete 按选项构造节点，协程池创建失败时返回错误，由 Chainor 记录并在 Invoke 时返回
+func (n *node) concrete(logger Logger) (*node, error) {
+	if n.opt.conflict != "" {
+		return nil, fmt.Errorf("%w: %s", ErrOptionConflict, n.opt.conflict)
+	}
+
 	{
 		switch n.opt.cct.mode() {
 		case workerpoolM:
-			if wp, err := ants.NewPool(n.opt.cct.count(),
-				ants.WithPanicHandler(n.panicHandler()),
-				ants.WithMaxBlockingTasks(DefaultMaxBlockingTasks)); err != nil {
-				log.Fatalf("failed to new task pool: %v\n", err)
-			} else {
-				n.workerpool = wp
+			// 具名协程池在运行时按名称获取，不在此处创建
+			if n.opt.poolName != "" {
+				break
 			}
+			wp, err := newPool(n.opt.cct.count(),
+				mergePoolOption(n.opt.poolOpts...), n.panicHandler(logger))
+			if err != nil {
+				return nil, fmt.Errorf("failed to new task pool: %w", err)
+			}
+			n.workerpool = wp
 		}
 	}
 	{
@@ -49,5 +66,18 @@ func (n *node) concrete() *node {
 			n.calls = append(n.calls, n.opt.funcs...)
 		}
 	}
-	return n
+	return n, nil
+}
+
+// derive 以 calls 派生出与 n 选项相同的节点，用于延迟绑定的 NextN，协程池与 n 共享
+func (n *node) derive(snap *Snapshot, calls TaskFuncs) *node {
+	opt := *n.opt
+	withGroup(snap, calls[1:])(&opt)
+	opt.lazy = false
+
+	return &node{
+		opt:        &opt,
+		calls:      calls,
+		workerpool: n.workerpool,
+	}
 }
@@ -8,10 +8,14 @@ type (
 	concurrent uint32
 
 	option struct {
-		name    string
-		param   any
-		props   M
-		timeout time.Duration
+		name     string
+		param    any
+		props    M
+		timeout  time.Duration
+		engine   *Engine
+		logger   Logger
+		registry *Registry
+		values   M
 
 		funcs []TaskFunc
 
@@ -20,8 +24,23 @@ type (
 
 		priority int
 		cct      concurrent
+		poolName string
+		poolOpts []PoolOption
+
+		// conflict 互斥可选项被同时指定时的说明
+		conflict string
+
+		maxIterations int
+		quorum        int
+		label         string
+		maxHops       int
 
 		skipResult bool
+		mapper     func([]any) []any
+		lazy       bool
+
+		// version NextN 构建时任务名的版本号
+		version uint64
 	}
 	optionable interface {
 		call(*option)
@@ -70,6 +89,12 @@ func mergeOption[T optionable](withFunc ...T) *option {
 	return o
 }
 
+func (o *option) checkConcurrent() {
+	if o.cct != 0 {
+		o.conflict = "WithParallel, WithWorkerPool and WithWorkerPoolN are mutually exclusive"
+	}
+}
+
 // WithParam Invoke 参数
 func WithParam(param any) Option {
 	return func(opt *option) {
@@ -91,12 +116,87 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
-func withSkipReuslt() TaskOption {
+// WithValues Invoke 预置的上下文值，节点任务可通过 TaskContext.Value、Get 读取
+func WithValues(values M) Option {
+	return func(opt *option) {
+		opt.values = values
+	}
+}
+
+// WithEngine Invoke 所属的 Engine，默认为全局 Engine
+func WithEngine(engine *Engine) Option {
+	return func(opt *option) {
+		opt.engine = engine
+	}
+}
+
+// WithSkipResult 节点仅用于副作用（如记录日志、写入上下文值），执行成功后下一节点的 lastResult 仍为该节点的 lastResult
+//
+// 节点的结果仍可通过 TaskContext.ResultOf 获取
+func WithSkipResult() TaskOption {
 	return func(opt *option) {
 		opt.skipResult = true
 	}
 }
 
+// WithResultMapper 节点执行成功后以 mapper 的返回值作为该节点的结果，可用于调整、展开或挑选结果，节点被跳过时不生效
+func WithResultMapper(mapper func(result []any) []any) TaskOption {
+	return func(opt *option) {
+		opt.mapper = mapper
+	}
+}
+
+// withGroup NextN 优先级分组的并行函数及版本号，覆盖用户的 WithParallelFunc；未指定 WithTaskName 时以任务名作为节点名称
+func withGroup(snap *Snapshot, funcs TaskFuncs) TaskOption {
+	return func(opt *option) {
+		opt.funcs = funcs
+		opt.version = snap.version
+		if opt.name == "" {
+			opt.name = snap.name
+		}
+	}
+}
+
+// WithMaxIterations While、Until 的最大迭代次数，超过时返回 ErrMaxIterations，不大于 0 时为 DefaultMaxIterations
+func WithMaxIterations(n int) TaskOption {
+	return func(opt *option) {
+		opt.maxIterations = n
+	}
+}
+
+// WithLabel 以 label 标记节点，作为 TaskContext.Goto 的跳转目标，同一条链路内不可重复，否则 Build 返回 ErrLabelDuplicated
+//
+// NextN 注册的多个优先级分组只标记第一个分组
+func WithLabel(label string) TaskOption {
+	return func(opt *option) {
+		opt.label = label
+	}
+}
+
+// WithMaxHops Invoke 的最大跳转次数，超过时返回 ErrMaxHops，不大于 0 时为 DefaultMaxHops
+func WithMaxHops(n int) Option {
+	return func(opt *option) {
+		opt.maxHops = n
+	}
+}
+
+// WithTaskName 节点名称，用于错误信息、TaskContext.ResultOf 等，NextN 注册的节点默认以任务名作为节点名称
+func WithTaskName(name string) TaskOption {
+	return func(opt *option) {
+		opt.name = name
+	}
+}
+
+// WithLazy 延迟绑定，仅对 NextN、CaseN、DefaultN 生效
+//
+// 构建链路时不校验任务名，而是在每次执行该节点时从注册表获取任务名当前版本的任务函数，
+// 因此可以引用构建链路之后才注册的任务，也可以通过 Replace 热替换任务实现；执行时任务名不存在则返回 ErrTaskNotFound
+func WithLazy() TaskOption {
+	return func(opt *option) {
+		opt.lazy = true
+	}
+}
+
 // WithTaskParam 节点任务参数
 func WithTaskParam(param any) TaskOption {
 	return func(opt *option) {
@@ -138,11 +238,19 @@ func WithAnyPassed(predicates ...func(result any) bool) TaskOption {
 	}
 }
 
+// WithQuorum 仅对 Parallel 生效（通过 ParallelWith 指定），通过的分支数达到 n 即完成，见 Parallel
+func WithQuorum(n int) TaskOption {
+	return func(opt *option) {
+		opt.quorum = n
+	}
+}
+
 // WithParallel 并行模式
 //
 // parallel 指定并行度，当与 WithParallelFunc 结合使用时，每个并行函数都会有相同的并行度
 func WithParallel(parallel uint16) TaskOption {
 	return func(opt *option) {
+		opt.checkConcurrent()
 		opt.cct = newConcurrent(parallelM, parallel)
 	}
 }
@@ -150,9 +258,26 @@ func WithParallel(parallel uint16) TaskOption {
 // WithWorkerPool 协程池模式，与 WithParallel 互斥
 //
 // pool 指定协程池容量，与 WithParallelFunc 结合使用时，所有任务函数共享同一份协程池
-func WithWorkerPool(pool uint16) TaskOption {
+// withFunc 为协程池可选项，例如 WithMaxBlockingTasks、WithNonblocking
+// 协程池在构建时创建，属于该链路，不再使用时通过 Chainor、Plan 的 Release 释放，执行过该链路的 Engine 关闭时同样释放
+func WithWorkerPool(pool uint16, withFunc ...PoolOption) TaskOption {
 	return func(opt *option) {
+		opt.checkConcurrent()
 		opt.cct = newConcurrent(workerpoolM, pool)
+		opt.poolName = ""
+		opt.poolOpts = withFunc
+	}
+}
+
+// WithWorkerPoolN 具名协程池模式，与 WithParallel 互斥
+//
+// name 为 RegisterPool 注册的协程池名称，执行时才按名称从 Invoke 所属的 Engine（见 WithEngine）获取协程池，不存在时节点任务返回 ErrPoolNotFound
+func WithWorkerPoolN(name string) TaskOption {
+	return func(opt *option) {
+		opt.checkConcurrent()
+		opt.cct = newConcurrent(workerpoolM, 0)
+		opt.poolName = name
+		opt.poolOpts = nil
 	}
 }
 
@@ -176,3 +301,17 @@ func WithChainorProps(props M) ChainorOption {
 		opt.props = props
 	}
 }
+
+// WithChainorLogger Chainor 日志，默认使用 SetLogger 设置的全局 Logger
+func WithChainorLogger(logger Logger) ChainorOption {
+	return func(opt *option) {
+		opt.logger = logger
+	}
+}
+
+// WithRegistry Chainor 的任务注册表，NextN、CaseN、DefaultN 从中获取任务函数，默认为全局注册表
+func WithRegistry(registry *Registry) ChainorOption {
+	return func(opt *option) {
+		opt.registry = registry
+	}
+}
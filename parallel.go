@@ -0,0 +1,115 @@
+package chainor
+
+import (
+	"sync"
+
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+type branchResult struct {
+	i   int
+	res []any
+	err error
+}
+
+// Parallel 并行执行多条子链路，全部结束后以各分支的结果（[]any）按分支顺序组成该节点的结果，即 result[i] 为第 i 个分支的结果
+//
+// 默认所有分支都需执行成功，任一分支失败则该节点以该错误失败；以下可选项按分支判定：
+// 1，WithAnyPassed 任意一个分支通过即完成，判定时传入的是该分支的结果，结果仅包含通过的分支，均未通过时返回 ErrNoPassed；
+// 2，WithQuorum 通过的分支数达到 n 即完成，结果按分支顺序仅包含通过的分支，无法达到时返回 ErrNoPassed；
+// 分支可读取原链路的上下文值，分支内写入的值及具名节点的结果在汇合时按分支顺序回写，未通过的分支全部丢弃；
+// 提前完成或失败时取消仍在执行的分支
+func (c *Chainor) Parallel(branches ...Case) *Chainor {
+	return c.ParallelWith(nil, branches...)
+}
+
+// ParallelWith 同 Parallel，withFunc 作用于整个汇合节点，例如 WithAnyPassed、WithQuorum、WithTaskName
+func (c *Chainor) ParallelWith(withFunc []TaskOption, branches ...Case) *Chainor {
+	plans := make([]*Plan, len(branches))
+	for i, branch := range branches {
+		plans[i] = c.compile(branch)
+	}
+
+	return c.nextFlow(func(s *step) ([]any, error) {
+		return joinBranches(s, plans)
+	}, withFunc...)
+}
+
+func joinBranches(s *step, plans []*Plan) ([]any, error) {
+	var (
+		opt    = s.n.opt
+		strict = opt.anyPassedFunc == nil && opt.quorum <= 0
+		need   = len(plans)
+	)
+	if !strict {
+		need = 1
+		if opt.quorum > 0 {
+			need = opt.quorum
+		}
+		if need > len(plans) {
+			return nil, ErrNoPassed
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		ch       = make(chan branchResult, len(plans))
+		branches = make([]*future, len(plans))
+	)
+	// 返回前取消仍在执行的分支并等待其退出，未汇合的分支不会再影响后续节点，也不会在调用结束后继续执行
+	defer func() {
+		for _, f := range branches {
+			f.ctx.cancel()
+		}
+		wg.Wait()
+	}()
+
+	wg.Add(len(plans))
+	for i, p := range plans {
+		i, p, f := i, p, s.f.branch()
+		branches[i] = f
+		threading.GoSafe(func() {
+			defer wg.Done()
+			res, err := f.exec(p, s.lastRes)
+			ch <- branchResult{i: i, res: res, err: err}
+		})
+	}
+
+	var (
+		results = make([][]any, len(plans))
+		passed  = make([]bool, len(plans))
+		count   int
+	)
+	for received := 0; received < len(plans) && count < need; received++ {
+		select {
+		case <-s.f.ctx.ctx.Done():
+			return nil, s.f.ctx.err()
+		case r := <-ch:
+			if isControl(r.err) {
+				branches[r.i].ctx.commit()
+				return nil, r.err
+			}
+			if strict && r.err != nil {
+				return nil, r.err
+			}
+			if r.err != nil || (opt.anyPassedFunc != nil && !opt.anyPassedFunc(r.res)) {
+				continue
+			}
+			results[r.i], passed[r.i] = r.res, true
+			count++
+		}
+	}
+	if count < need {
+		return nil, ErrNoPassed
+	}
+
+	// 通过的分支按分支顺序回写
+	res := make([]any, 0, count)
+	for i := range plans {
+		if passed[i] {
+			branches[i].ctx.commit()
+			res = append(res, results[i])
+		}
+	}
+	return res, nil
+}
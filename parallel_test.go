@@ -0,0 +1,156 @@
+package chainor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParallel(t *testing.T) {
+	branch := func(v any, delay time.Duration, err error) Case {
+		return func(c *Chainor) {
+			c.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0], nil
+			}).Next(func(ctx *TaskContext, lastResult []any) (result any, e error) {
+				time.Sleep(delay)
+				if err != nil {
+					return nil, err
+				}
+				return []any{lastResult[0], v}, nil
+			})
+		}
+	}
+	start := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+		return 0, nil
+	}
+
+	Convey("Parallel joins all branches in order", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		Invoke(NewChainor().Next(start).Parallel(
+			branch("a", 100*time.Millisecond, nil),
+			branch("b", 0, nil),
+		).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return len(lastResult), nil
+		}, WithSkipResult()), func(result []any) {
+			c.So(result, ShouldResemble, []any{
+				[]any{[]any{0, "a"}},
+				[]any{[]any{0, "b"}},
+			})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Next(start).Parallel(
+			branch("a", 0, nil),
+			branch("b", 0, errors.New("branch err")),
+		), nil, func(err error) {
+			c.So(err.Error(), ShouldEqual, "branch err")
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+
+	Convey("Parallel with WithAnyPassed and WithQuorum", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(4)
+
+		branches := []Case{
+			branch("a", 300*time.Millisecond, nil),
+			branch("b", 0, errors.New("branch err")),
+			branch("c", 50*time.Millisecond, nil),
+			branch("d", 100*time.Millisecond, nil),
+		}
+
+		Invoke(NewChainor().Next(start).ParallelWith([]TaskOption{WithAnyPassed()}, branches...), func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{[]any{0, "c"}}})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Next(start).ParallelWith([]TaskOption{WithAnyPassed(func(result any) bool {
+			return result.([]any)[0].([]any)[1] == "a"
+		})}, branches...), func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{[]any{0, "a"}}})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Next(start).ParallelWith([]TaskOption{WithQuorum(2)}, branches...), func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{[]any{0, "c"}}, []any{[]any{0, "d"}}})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Next(start).ParallelWith([]TaskOption{WithQuorum(4)}, branches...), nil, func(err error) {
+			c.So(err, ShouldEqual, ErrNoPassed)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+}
+
+func TestParallelValues(t *testing.T) {
+	Convey("Values written by branches", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		var loserDone sync.WaitGroup
+		loserDone.Add(1)
+
+		writer := func(v string, delay time.Duration, done func()) Case {
+			return func(c *Chainor) {
+				c.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					c1 := ctx.Value("shared")
+					time.Sleep(delay)
+					ctx.WithValue("k", v)
+					ctx.WithValue(v, c1)
+					if done != nil {
+						done()
+					}
+					return v, nil
+				}, WithTaskName("writer-"+v))
+			}
+		}
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				ctx.WithValue("shared", 1)
+				return nil, nil
+			}).
+			ParallelWith([]TaskOption{WithAnyPassed()},
+				writer("winner", 0, nil),
+				writer("loser", 200*time.Millisecond, loserDone.Done),
+			).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				_, ok := ctx.ResultOf("writer-loser")
+				c.So(ok, ShouldBeFalse)
+				// 等待落败的分支写入后再读取
+				loserDone.Wait()
+				return ctx.Value("k"), nil
+			})
+
+		InvokeWithValues(chn, func(result []any, values Values) {
+			c.So(result, ShouldResemble, []any{"winner"})
+			c.So(values.Keys(), ShouldResemble, []string{"k", "shared", "winner"})
+			c.So(values.Value("winner"), ShouldEqual, 1)
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor().Parallel(
+			writer("a", 50*time.Millisecond, nil),
+			writer("b", 0, nil),
+		).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			a, _ := ctx.ResultOf("writer-a")
+			return []any{ctx.Value("k"), a[0]}, nil
+		}), func(result []any) {
+			// 按分支顺序回写
+			c.So(result, ShouldResemble, []any{[]any{"b", "a"}})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
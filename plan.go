@@ -0,0 +1,52 @@
+package chainor
+
+// Build 校验链路并生成执行计划，Plan 可被多次 Invoke
+//
+// 校验内容包括：
+// 1，构建链路时遇到的错误，例如 NextN 的任务名不存在（ErrTaskNotFound）、可选项冲突（ErrOptionConflict）、
+// Switch2 结束后继续注册节点（ErrChainForked）、Switch 结束后继续注册 case（ErrSwitchClosed）、协程池创建失败等；
+// 2，Switch 和 Switch2 是否均以 Default、DefaultN、End 或 EndStrict 结束（ErrSwitchUnterminated）；
+// 3，WithLabel 的标签是否重复（ErrLabelDuplicated）；
+func (c *Chainor) Build() (*Plan, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	for _, s := range c.switches {
+		if !s.closed {
+			return nil, ErrSwitchUnterminated
+		}
+	}
+
+	p := &Plan{
+		opt:   c.opt,
+		nodes: make([]*node, 0, c.queue.Size()),
+		pools: c.pools,
+	}
+	iter := c.queue.Iterator()
+	for iter.HasNext() {
+		p.nodes = append(p.nodes, iter.Next().(*node))
+	}
+
+	var err error
+	if p.labels, err = labels(p.nodes); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plan) logger() Logger {
+	if p.opt.logger != nil {
+		return p.opt.logger
+	}
+	return globalLogger()
+}
+
+// Release 释放 Plan 的节点协程池，同 Chainor.Release
+func (p *Plan) Release() {
+	p.pools.release()
+}
+
+// Build 实现 Invocable，返回 Plan 本身
+func (p *Plan) Build() (*Plan, error) {
+	return p, nil
+}
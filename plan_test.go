@@ -0,0 +1,257 @@
+package chainor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuild(t *testing.T) {
+	task := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+		return 1, nil
+	}
+
+	Convey("Build with unknown name", t, func() {
+		_, err := NewChainor().Next(task).NextN("build-unknown").Build()
+		So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+	})
+
+	Convey("Build with unterminated switch", t, func(c C) {
+		chn := NewChainor()
+		chn.Next(task).Switch(func(lastResult []any) (result any) {
+			return 1
+		}).Case(1, task)
+
+		_, err := chn.Build()
+		So(err, ShouldEqual, ErrSwitchUnterminated)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrSwitchUnterminated)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+
+	Convey("Build with conflicting options", t, func() {
+		_, err := NewChainor().Next(task, WithParallel(2), WithWorkerPool(2)).Build()
+		So(errors.Is(err, ErrOptionConflict), ShouldBeTrue)
+	})
+
+	Convey("Build with next after Switch2", t, func() {
+		chn := NewChainor()
+		chn.Next(task).Switch2(func(lastResult []any) (result any) {
+			return 1
+		}).Default(func(c *Chainor) {
+			c.Next(task)
+		})
+		chn.Next(task)
+
+		_, err := chn.Build()
+		So(err, ShouldEqual, ErrChainForked)
+	})
+
+	Convey("Build with unknown name in Switch2 branch", t, func() {
+		chn := NewChainor()
+		chn.Next(task).Switch2(func(lastResult []any) (result any) {
+			return 1
+		}).Default(func(c *Chainor) {
+			c.NextN("build-unknown")
+		})
+
+		_, err := chn.Build()
+		So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+	})
+
+	Convey("Plan is fixed at build time", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().Next(task)
+		p, err := chn.Build()
+		So(err, ShouldBeNil)
+
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 2, nil
+		})
+
+		Invoke(p, func(result []any) {
+			c.So(result, ShouldResemble, []any{1})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
+
+func TestSwitchClosed(t *testing.T) {
+	task := func(v any) TaskFunc {
+		return func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return v, nil
+		}
+	}
+
+	Convey("Switch is fixed once terminated", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor()
+		sw := chn.Switch(func(lastResult []any) (result any) {
+			return 2
+		}).Case(1, task(1))
+		sw.Default(task("default"))
+
+		p, err := chn.Build()
+		So(err, ShouldBeNil)
+
+		sw.Case(2, task(2))
+		sw.Default(task("again"))
+
+		_, err = chn.Build()
+		So(err, ShouldEqual, ErrSwitchClosed)
+		So(p.nodes, ShouldHaveLength, 1)
+
+		Invoke(p, func(result []any) {
+			c.So(result, ShouldResemble, []any{"default"})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+
+	Convey("Switch2 is fixed once terminated", t, func() {
+		chn := NewChainor()
+		sw := chn.Switch2(func(lastResult []any) (result any) {
+			return 1
+		})
+		sw.End()
+		sw.Isolate().Case(1, func(c *Chainor) {
+			c.Next(task(1))
+		})
+
+		_, err := chn.Build()
+		So(err, ShouldEqual, ErrSwitchClosed)
+	})
+}
+
+func TestConcurrentInvoke(t *testing.T) {
+	Convey("Invoke one plan concurrently", t, func(c C) {
+		sum := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			total := 0
+			for _, v := range lastResult {
+				total += v.(int)
+			}
+			return total, nil
+		}
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			ctx.WithValue("param", ctx.Param())
+			return ctx.Param(), nil
+		}).Switch(func(lastResult []any) (result any) {
+			return lastResult[0].(int) % 2
+		}).Case(0, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0].(int) * 10, nil
+		}).Default(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0].(int)*10 + 1, nil
+		}).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0], nil
+		}, WithParallelFunc(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return lastResult[0], nil
+		}), WithParallel(2)).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0].(int) % 3
+		}).Case(0, func(c1 *Chainor) {
+			c1.Next(sum)
+		}).Default(func(c2 *Chainor) {
+			c2.Next(sum).Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				if ctx.Value("param") != ctx.Param() {
+					return nil, errors.New("value leaked between invocations")
+				}
+				return -lastResult[0].(int), nil
+			})
+		})
+
+		p, err := chn.Build()
+		So(err, ShouldBeNil)
+
+		const count = 2000
+		results := make([]any, count)
+		errs := make([]error, count)
+
+		wg := sync.WaitGroup{}
+		wg.Add(count)
+		for i := 0; i < count; i++ {
+			i := i
+			go Invoke(p, func(result []any) {
+				results[i] = result[0]
+				wg.Done()
+			}, func(err error) {
+				errs[i] = err
+				wg.Done()
+			}, WithParam(i))
+		}
+		wg.Wait()
+
+		for i := 0; i < count; i++ {
+			v := 4 * (i*10 + i%2)
+			if (i*10+i%2)%3 != 0 {
+				v = -v
+			}
+			So(errs[i], ShouldBeNil)
+			So(results[i], ShouldEqual, v)
+		}
+	})
+}
+
+func TestNextC(t *testing.T) {
+	Convey("Chain as a node", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		sub := NewChainor(WithChainorName("sub")).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(ctx.ChainorName(), ShouldEqual, "sub")
+				c.So(ctx.Value("k"), ShouldEqual, "v")
+				ctx.WithValue("sub", true)
+				if ctx.Param() == "fail" {
+					return nil, errors.New("sub err")
+				}
+				return lastResult[0].(int) * 2, nil
+			})
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				ctx.WithValue("k", "v")
+				return 1, nil
+			}).
+			NextC(sub, WithTaskName("sub")).
+			NextC(sub).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(ctx.Value("sub"), ShouldEqual, true)
+				res, _ := ctx.ResultOf("sub")
+				return []any{res[0], lastResult[0]}, nil
+			})
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{2, 4}})
+			wg.Done()
+		}, nil)
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err.Error(), ShouldEqual, "sub err")
+			wg.Done()
+		}, WithParam("fail"))
+
+		wg.Wait()
+	})
+
+	Convey("Chain as a node with build error", t, func() {
+		_, err := NewChainor().NextC(NewChainor().NextN("nextc-unknown")).Build()
+		So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,145 @@
+package chainor
+
+import (
+	"sync"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+type (
+	poolOption struct {
+		maxBlockingTasks int
+		nonblocking      bool
+	}
+
+	PoolOption func(opt *poolOption)
+
+	// poolBucket 具名协程池（RegisterPool），每个 Engine 各自一份，Shutdown 时释放
+	poolBucket struct {
+		rw sync.RWMutex
+		mp map[string]*ants.Pool
+	}
+
+	// nodePools 节点协程池（WithWorkerPool），属于创建它的 Chainor 及其 Plan，通过 Release 释放
+	nodePools struct {
+		mu    sync.Mutex
+		pools []*ants.Pool
+	}
+)
+
+func newPoolBucket() *poolBucket {
+	return &poolBucket{
+		mp: make(map[string]*ants.Pool),
+	}
+}
+
+func mergePoolOption(withFunc ...PoolOption) *poolOption {
+	o := &poolOption{
+		maxBlockingTasks: DefaultMaxBlockingTasks,
+	}
+
+	for i := range withFunc {
+		withFunc[i](o)
+	}
+	return o
+}
+
+func newPool(size int, opt *poolOption, panicHandler func(interface{})) (*ants.Pool, error) {
+	return ants.NewPool(size,
+		ants.WithPanicHandler(panicHandler),
+		ants.WithMaxBlockingTasks(opt.maxBlockingTasks),
+		ants.WithNonblocking(opt.nonblocking))
+}
+
+// WithMaxBlockingTasks 协程池满时允许阻塞等待的最大任务数，默认为 DefaultMaxBlockingTasks，0 表示不限制
+func WithMaxBlockingTasks(maxBlockingTasks int) PoolOption {
+	return func(opt *poolOption) {
+		opt.maxBlockingTasks = maxBlockingTasks
+	}
+}
+
+// WithNonblocking 非阻塞模式，协程池满时直接提交失败，节点任务返回 ants.ErrPoolOverload
+func WithNonblocking() PoolOption {
+	return func(opt *poolOption) {
+		opt.nonblocking = true
+	}
+}
+
+// RegisterPool 在全局 Engine 上注册具名协程池，详见 Engine.RegisterPool
+func RegisterPool(name string, size int, withFunc ...PoolOption) error {
+	return defaultEngine.RegisterPool(name, size, withFunc...)
+}
+
+// ReleasePool 释放全局 Engine 上的具名协程池，详见 Engine.ReleasePool
+func ReleasePool(name string) {
+	defaultEngine.ReleasePool(name)
+}
+
+func (p *poolBucket) register(name string, size int, withFunc ...PoolOption) error {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if _, ok := p.mp[name]; ok {
+		return ErrPoolExists
+	}
+
+	wp, err := newPool(size, mergePoolOption(withFunc...), func(err interface{}) {
+		logPanic(globalLogger(), err)
+	})
+	if err != nil {
+		return err
+	}
+	p.mp[name] = wp
+	return nil
+}
+
+func (p *poolBucket) release(name string) {
+	p.rw.Lock()
+	wp, ok := p.mp[name]
+	delete(p.mp, name)
+	p.rw.Unlock()
+
+	if ok {
+		wp.Release()
+	}
+}
+
+func (p *poolBucket) get(name string) (*ants.Pool, bool) {
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+
+	wp, ok := p.mp[name]
+	return wp, ok
+}
+
+func (p *poolBucket) releaseAll() {
+	p.rw.Lock()
+	pools := make([]*ants.Pool, 0, len(p.mp))
+	for name, wp := range p.mp {
+		pools = append(pools, wp)
+		delete(p.mp, name)
+	}
+	p.rw.Unlock()
+
+	for _, wp := range pools {
+		wp.Release()
+	}
+}
+
+func (p *nodePools) add(wp *ants.Pool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pools = append(p.pools, wp)
+}
+
+func (p *nodePools) release() {
+	p.mu.Lock()
+	pools := p.pools
+	p.pools = nil
+	p.mu.Unlock()
+
+	for _, wp := range pools {
+		wp.Release()
+	}
+}
@@ -0,0 +1,159 @@
+package chainor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerPoolN(t *testing.T) {
+	Convey("Named workerpool", t, func(c C) {
+		So(RegisterPool("wp1", 2), ShouldBeNil)
+		So(RegisterPool("wp1", 2), ShouldEqual, ErrPoolExists)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 2, nil
+			}, WithParallelFunc(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 7, nil
+			}, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 8, nil
+			}), WithWorkerPoolN("wp1"))
+
+		Invoke(chn, func(result []any) {
+			c.So(cloneAndSortResult(result), ShouldResemble, []any{2, 7, 8})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+
+		Convey("Released workerpool", func(c C) {
+			ReleasePool("wp1")
+
+			wg = sync.WaitGroup{}
+			wg.Add(1)
+
+			Invoke(chn, nil, func(err error) {
+				c.So(err, ShouldEqual, ErrPoolNotFound)
+				wg.Done()
+			})
+
+			wg.Wait()
+		})
+	})
+
+	Convey("Full workerpool respects timeout", t, func(c C) {
+		So(RegisterPool("wp3", 1), ShouldBeNil)
+		defer ReleasePool("wp3")
+
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		long := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(time.Second)
+				return 1, nil
+			}, WithWorkerPoolN("wp3"))
+
+		Invoke(long, func(result []any) {
+			wg.Done()
+		}, nil)
+
+		time.Sleep(50 * time.Millisecond)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 2, nil
+			}, WithWorkerPoolN("wp3"))
+
+		start := time.Now()
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ErrTimeout)
+			c.So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+			wg.Done()
+		}, WithTimeout(100*time.Millisecond))
+
+		wg.Wait()
+	})
+
+	Convey("Release node workerpools", t, func(c C) {
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}, WithWorkerPool(2)).
+			Switch(func(lastResult []any) any {
+				return lastResult[0]
+			}).
+			Case(1, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 2, nil
+			}).
+			End().
+			While(func(lastResult []any) bool {
+				return lastResult[0].(int) < 4
+			}, func(c *Chainor) {
+				c.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return lastResult[0].(int) + 1, nil
+				}, WithWorkerPool(2))
+			})
+
+		p, err := chn.Build()
+		So(err, ShouldBeNil)
+
+		pools := append([]*ants.Pool(nil), p.pools.pools...)
+		So(len(pools), ShouldEqual, 2)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		Invoke(p, func(result []any) {
+			c.So(result, ShouldResemble, []any{4})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+
+		p.Release()
+		for _, wp := range pools {
+			So(wp.IsClosed(), ShouldBeTrue)
+		}
+
+		wg.Add(1)
+
+		Invoke(p, nil, func(err error) {
+			c.So(err, ShouldEqual, ants.ErrPoolClosed)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+
+	Convey("Nonblocking workerpool", t, func(c C) {
+		So(RegisterPool("wp2", 1, WithNonblocking()), ShouldBeNil)
+		defer ReleasePool("wp2")
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(100 * time.Millisecond)
+				return 1, nil
+			}, WithParallelFunc(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(100 * time.Millisecond)
+				return 2, nil
+			}), WithWorkerPoolN("wp2"))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(err, ShouldEqual, ants.ErrPoolOverload)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+}
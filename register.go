@@ -1,23 +1,228 @@
 package chainor
 
-var nodeMap = defalutMapBucket
+import (
+	"sort"
+	"sync"
+)
 
-// Register 全局有效，注册任务函数，通过 NextN 调用
+type (
+	// Registry 任务注册表，NextN、CaseN、DefaultN 通过任务名从中获取任务函数
+	//
+	// 通过 WithRegistry 为 Chainor 指定注册表，未指定时使用全局注册表
+	Registry struct {
+		// rw 保证任务函数与版本号的修改、读取是原子的
+		rw       sync.RWMutex
+		nodes    mapBucket
+		versions map[string]uint64
+		seq      uint64
+	}
+
+	// Snapshot 任务名在某一版本下注册的任务函数，不随之后的 Register、Replace、Unregister 变化
+	Snapshot struct {
+		name    string
+		version uint64
+		nodes   []*node
+	}
+)
+
+var defaultRegistry = &Registry{
+	nodes:    defalutMapBucket,
+	versions: make(map[string]uint64),
+}
+
+// NewRegistry 返回一个独立的任务注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		nodes:    newSyncMapBucket(),
+		versions: make(map[string]uint64),
+	}
+}
+
+// DefaultRegistry 返回全局注册表，Register 等包级函数均作用于该注册表
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register 注册任务函数，通过 NextN 调用，优先级为 P_Normal，详见 RegisterWithPriority
 //
 // name 任务名称
 // task 任务函数，允许设置多个
 //
-// 注：原本的设计是允许配置优先级的，函数原型为 Register(name string, task TaskFunc, priority ...int)，后决定废除优先级设置，原因有以下几点：
-// 1，该包为全异步链路实现，优先级设置的意义不大，完全可以通过拼接链路节点自行实现；
-// 2，引入优先级之后，不仅会增加场景的复杂度，且会在多种场景下有歧义，例如 WithParallelFunc、WithAnyPassed 时，高低优先级任务函数具体是如何运转的等等问题；
-// 另外，虽然废除了优先级设置，但保留了当前已经实现的部分优先级功能，该部分功能不影响现有机制
-func Register(name string, task ...TaskFunc) {
-	p := P_Normal
+// 同名重复注册时追加任务函数，NextN 时与已注册的同优先级任务函数并行执行；需要覆盖时使用 Replace
+// 每次注册都会产生新的版本，已经通过 NextN 构建的链路保持构建时的版本
+func (r *Registry) Register(name string, task ...TaskFunc) {
+	r.RegisterWithPriority(name, P_Normal, task...)
+}
 
+// RegisterWithPriority 按优先级注册任务函数，通过 NextN 调用
+//
+// 同一任务名下，不同优先级的任务函数分组后作为依次执行的子节点，优先级高的先执行，上一分组的结果作为下一分组的 lastResult；
+// 同一优先级的任务函数在同一个子节点内并行执行
+//
+// NextN 的可选项对每个子节点分别生效，其中：
+// 1，WithAnyPassed 按分组判定，每个分组都需有任务函数通过，否则返回 ErrNoPassed，通过的结果作为下一分组的 lastResult；
+// 2，WithParallelFunc 的并行函数只并入优先级最高的分组；
+// 3，WithSkipped 按分组判定，判定时传入的是该分组的 lastResult；
+// 4，WithResultMapper 分别转换每个分组的结果，WithSkipResult 对每个分组生效，即整个 NextN 节点的 lastResult 保持不变；
+func (r *Registry) RegisterWithPriority(name string, priority int, task ...TaskFunc) {
 	if len(task) > 0 {
-		nodeMap.put(name, &node{
+		r.rw.Lock()
+		defer r.rw.Unlock()
+
+		r.nodes.put(name, &node{
 			calls:    task,
-			priority: p,
+			priority: priority,
 		})
+		r.bump(name)
+	}
+}
+
+// Unregister 注销任务名下的所有任务函数，已经通过 NextN 构建的链路保持构建时的版本
+func (r *Registry) Unregister(name string) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	if r.nodes.exist(name) {
+		r.nodes.remove(name)
+		r.bump(name)
+	}
+}
+
+// Replace 以 task 替换任务名下的所有任务函数，优先级为 P_Normal，已经通过 NextN 构建的链路保持构建时的版本
+//
+// task 为空时等同于 Unregister
+func (r *Registry) Replace(name string, task ...TaskFunc) {
+	if len(task) == 0 {
+		r.Unregister(name)
+		return
+	}
+
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	r.nodes.replace(name, &node{
+		calls:    task,
+		priority: P_Normal,
+	})
+	r.bump(name)
+}
+
+// bump 更新任务名的版本号，版本号在注册表内单调递增，调用方需持有写锁
+func (r *Registry) bump(name string) {
+	r.seq++
+	r.versions[name] = r.seq
+}
+
+// Version 返回任务名当前的版本号，从未注册过时返回 0
+func (r *Registry) Version(name string) uint64 {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	return r.versions[name]
+}
+
+// Lookup 返回任务名当前版本的快照，任务名不存在时返回 false
+func (r *Registry) Lookup(name string) (*Snapshot, bool) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	if !r.nodes.exist(name) {
+		return nil, false
+	}
+
+	snap := &Snapshot{
+		name:    name,
+		version: r.versions[name],
+		nodes:   make([]*node, 0, r.nodes.lenB(name)),
+	}
+	iter := r.nodes.iteratorB(name)
+	for iter.HasNext() {
+		snap.nodes = append(snap.nodes, iter.Next().(*node))
+	}
+	return snap, true
+}
+
+// List 返回已注册的任务名，按字典序排列
+func (r *Registry) List() []string {
+	names := make([]string, 0)
+	r.nodes.eachKey(func(bkey any) {
+		names = append(names, bkey.(string))
+	})
+	sort.Strings(names)
+	return names
+}
+
+// Exist 任务名是否已注册
+func (r *Registry) Exist(name string) bool {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	return r.nodes.exist(name)
+}
+
+// Name 任务名
+func (s *Snapshot) Name() string {
+	return s.name
+}
+
+// Version 快照的版本号
+func (s *Snapshot) Version() uint64 {
+	return s.version
+}
+
+// Register 全局有效，注册任务函数，通过 NextN 调用，详见 Registry.Register
+func Register(name string, task ...TaskFunc) {
+	defaultRegistry.Register(name, task...)
+}
+
+// RegisterWithPriority 全局有效，按优先级注册任务函数，通过 NextN 调用，详见 Registry.RegisterWithPriority
+func RegisterWithPriority(name string, priority int, task ...TaskFunc) {
+	defaultRegistry.RegisterWithPriority(name, priority, task...)
+}
+
+// Unregister 全局有效，注销任务名下的所有任务函数，详见 Registry.Unregister
+func Unregister(name string) {
+	defaultRegistry.Unregister(name)
+}
+
+// Replace 全局有效，替换任务名下的所有任务函数，详见 Registry.Replace
+func Replace(name string, task ...TaskFunc) {
+	defaultRegistry.Replace(name, task...)
+}
+
+// collate 按优先级从高到低对任务函数分组，i 为分组序号
+func (s *Snapshot) collate(cb func(i int, tasks TaskFuncs)) {
+	tempMap := newSyncMapBucket()
+	keyArray := make([]int, 0, len(s.nodes))
+
+	for _, n := range s.nodes {
+		tempMap.put(n.priority, n)
+	}
+
+	tempMap.eachKey(func(bkey any) {
+		keyArray = append(keyArray, bkey.(int))
+	})
+	sort.SliceStable(keyArray, func(i, j int) bool {
+		return keyArray[i] > keyArray[j]
+	})
+
+	for i := 0; i < len(keyArray); i++ {
+		iter := tempMap.iteratorB(keyArray[i])
+		var withs TaskFuncs
+
+		for iter.HasNext() {
+			n := iter.Next().(*node)
+			withs = append(withs, n.calls...)
+		}
+		cb(i, withs)
+	}
+}
+
+// groupCalls 第 i 个优先级分组的任务函数，funcs 为 WithParallelFunc 的并行函数，只并入优先级最高的分组
+func groupCalls(i int, tasks TaskFuncs, funcs []TaskFunc) TaskFuncs {
+	calls := append(TaskFuncs{}, tasks...)
+	if i == 0 {
+		calls = append(calls, funcs...)
 	}
+	return calls
 }
@@ -0,0 +1,291 @@
+package chainor
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistry(t *testing.T) {
+	Convey("Registry instance", t, func(c C) {
+		r := NewRegistry()
+		r.Register("r1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 1, nil
+		})
+		r.Register("r2", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 2, nil
+		})
+
+		So(r.List(), ShouldResemble, []string{"r1", "r2"})
+		So(DefaultRegistry().Exist("r1"), ShouldBeFalse)
+
+		// 未指定注册表时从全局注册表获取
+		_, err := NewChainor().NextN("r1").Build()
+		So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor(WithRegistry(r)).NextN("r1")
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{1})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+
+		Convey("Replace and unregister", func(c C) {
+			r.Replace("r1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 11, nil
+			})
+			r.Unregister("r2")
+			So(r.List(), ShouldResemble, []string{"r1"})
+
+			wg = sync.WaitGroup{}
+			wg.Add(2)
+
+			// 已构建的链路不受影响
+			Invoke(chn, func(result []any) {
+				c.So(result, ShouldResemble, []any{1})
+				wg.Done()
+			}, nil)
+
+			Invoke(NewChainor(WithRegistry(r)).NextN("r1"), func(result []any) {
+				c.So(result, ShouldResemble, []any{11})
+				wg.Done()
+			}, nil)
+
+			wg.Wait()
+
+			_, err := NewChainor(WithRegistry(r)).NextN("r2").Build()
+			So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+		})
+	})
+}
+
+func TestRegistryVersion(t *testing.T) {
+	Convey("Registry version", t, func(c C) {
+		r := NewRegistry()
+		So(r.Version("v1"), ShouldEqual, 0)
+
+		r.Register("v1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return ctx.TaskVersion(), nil
+		})
+		v1 := r.Version("v1")
+		So(v1, ShouldBeGreaterThan, 0)
+
+		snap, ok := r.Lookup("v1")
+		So(ok, ShouldBeTrue)
+		So(snap.Name(), ShouldEqual, "v1")
+		So(snap.Version(), ShouldEqual, v1)
+
+		old := NewChainor(WithRegistry(r)).NextN("v1")
+
+		r.Replace("v1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return -1, nil
+		})
+		So(r.Version("v1"), ShouldBeGreaterThan, v1)
+
+		// 重复注册为追加，与已注册的任务函数并行执行
+		r.Register("v1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return -2, nil
+		})
+		v3 := r.Version("v1")
+
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		Invoke(old, func(result []any) {
+			c.So(result, ShouldResemble, []any{v1})
+			wg.Done()
+		}, nil)
+
+		Invoke(NewChainor(WithRegistry(r)).NextN("v1").Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return cloneAndSortResult(lastResult), nil
+		}), func(result []any) {
+			c.So(result, ShouldResemble, []any{[]any{-2, -1}})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+
+		r.Unregister("v1")
+		So(r.Version("v1"), ShouldBeGreaterThan, v3)
+		_, ok = r.Lookup("v1")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestNextNLazy(t *testing.T) {
+	Convey("NextN with lazy", t, func(c C) {
+		r := NewRegistry()
+		chn := NewChainor(WithRegistry(r)).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}).
+			NextN("lazy1", WithLazy(), WithTaskParam(5))
+
+		_, err := chn.Build()
+		So(err, ShouldBeNil)
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		Invoke(chn, nil, func(err error) {
+			c.So(errors.Is(err, ErrTaskNotFound), ShouldBeTrue)
+			wg.Done()
+		})
+
+		wg.Wait()
+
+		Convey("Registered after build", func(c C) {
+			r.Register("lazy1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(ctx.TaskParam(), ShouldEqual, 5)
+				c.So(lastResult, ShouldResemble, []any{1})
+				return 2, nil
+			}, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 3, nil
+			})
+
+			wg = sync.WaitGroup{}
+			wg.Add(1)
+
+			Invoke(chn, func(result []any) {
+				c.So(cloneAndSortResult(result), ShouldResemble, []any{2, 3})
+				wg.Done()
+			}, nil)
+
+			wg.Wait()
+
+			r.Replace("lazy1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return ctx.TaskVersion(), nil
+			})
+
+			wg = sync.WaitGroup{}
+			wg.Add(1)
+
+			Invoke(chn, func(result []any) {
+				c.So(result, ShouldResemble, []any{r.Version("lazy1")})
+				wg.Done()
+			}, nil)
+
+			wg.Wait()
+		})
+
+		Convey("Lazy and eager NextN behave the same", func(c C) {
+			r.Register("lazy2", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return lastResult[0].(int) + 1, nil
+			})
+
+			for _, lazy := range []bool{true, false} {
+				var skips int32
+				withs := []TaskOption{WithSkipResult(), WithTaskName("x"), WithSkipped(func(result []any) bool {
+					atomic.AddInt32(&skips, 1)
+					return false
+				})}
+				if lazy {
+					withs = append(withs, WithLazy())
+				}
+
+				wg := sync.WaitGroup{}
+				wg.Add(1)
+
+				chn := NewChainor(WithRegistry(r)).
+					Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+						return 1, nil
+					}).
+					NextN("lazy2", withs...).
+					Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+						x, _ := ctx.ResultOf("x")
+						return []any{lastResult[0], x[0]}, nil
+					})
+
+				Invoke(chn, func(result []any) {
+					c.So(result, ShouldResemble, []any{[]any{1, 2}})
+					wg.Done()
+				}, nil)
+
+				wg.Wait()
+				So(atomic.LoadInt32(&skips), ShouldEqual, 1)
+			}
+		})
+	})
+}
+
+func TestRegisterWithPriority(t *testing.T) {
+	Convey("Register with priority", t, func(c C) {
+		r := NewRegistry()
+		r.RegisterWithPriority("p1", P_Low, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			c.So(lastResult, ShouldResemble, []any{30})
+			return 4, nil
+		})
+		r.RegisterWithPriority("p1", P_High, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			c.So(lastResult, ShouldResemble, []any{0})
+			return 1, nil
+		}, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 2, nil
+		})
+		r.Register("p1", func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			c.So(cloneAndSortResult(lastResult), ShouldResemble, []any{1, 2, 3})
+			return 30, nil
+		})
+
+		for _, lazy := range []bool{false, true} {
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+
+			withs := []TaskOption{WithParallelFunc(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				// 只并入优先级最高的分组
+				c.So(lastResult, ShouldResemble, []any{0})
+				return 3, nil
+			})}
+			if lazy {
+				withs = append(withs, WithLazy())
+			}
+
+			chn := NewChainor(WithRegistry(r)).
+				Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					return 0, nil
+				}).
+				NextN("p1", withs...)
+
+			Invoke(chn, func(result []any) {
+				c.So(result, ShouldResemble, []any{4})
+				wg.Done()
+			}, nil)
+
+			wg.Wait()
+		}
+	})
+
+	Convey("Register with priority and any passed", t, func(c C) {
+		r := NewRegistry()
+		r.RegisterWithPriority("p2", P_High, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 1, nil
+		}, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 2, nil
+		})
+		r.RegisterWithPriority("p2", P_Low, func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			c.So(lastResult, ShouldResemble, []any{2})
+			return 3, nil
+		})
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor(WithRegistry(r)).NextN("p2", WithAnyPassed(func(result any) bool {
+			return result != 1
+		}))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{3})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
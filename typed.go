@@ -0,0 +1,100 @@
+package chainor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Typed 将泛型任务函数包装为 TaskFunc，由框架完成 lastResult 到 In 的转换
+//
+// 转换规则依次为：
+// 1，In 为 any 或 []any 时，始终直接使用 lastResult（[]any），不论 lastResult 有几个值，结构不随上游的并行度变化；
+// 2，lastResult 只有一个值且类型为 In 时，直接使用该值；
+// 3，lastResult 为空，或 In 可为 nil（指针、map、切片、接口等）且 lastResult 只有一个 nil 值时，使用 In 的零值；
+// 4，In 为切片类型时，lastResult 的每个值都需为切片的元素类型；
+// 其他情况返回 ErrTypeMismatch，错误信息中包含节点名称（见 WithTaskName）
+func Typed[In, Out any](task func(ctx *TaskContext, in In) (Out, error)) TaskFunc {
+	return func(ctx *TaskContext, lastResult []any) (any, error) {
+		in, ok := convert[In](lastResult)
+		if !ok {
+			return nil, fmt.Errorf("%w: node %s expects %s, got %s",
+				ErrTypeMismatch, ctx.nodeName(), typeName[In](), describe(lastResult))
+		}
+		return task(ctx, in)
+	}
+}
+
+// InvokeTyped 与 Invoke 相同，但将链路的最终结果按 Typed 的规则转换为 Out，转换失败时以 ErrTypeMismatch 失败
+func InvokeTyped[Out any](c Invocable, onSuccess func(result Out), onFailed func(err error), withFunc ...Option) {
+	Invoke(c, func(result []any) {
+		out, ok := convert[Out](result)
+		if !ok {
+			if onFailed != nil {
+				onFailed(fmt.Errorf("%w: result expects %s, got %s",
+					ErrTypeMismatch, typeName[Out](), describe(result)))
+			}
+			return
+		}
+		if onSuccess != nil {
+			onSuccess(out)
+		}
+	}, onFailed, withFunc...)
+}
+
+func convert[T any](values []any) (T, bool) {
+	var zero T
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t == anyType || t == anySliceType {
+		return any(values).(T), true
+	}
+
+	if len(values) == 1 {
+		if v, ok := values[0].(T); ok {
+			return v, true
+		}
+	}
+	if len(values) == 0 || len(values) == 1 && values[0] == nil && nilable(t) {
+		return zero, true
+	}
+
+	if t.Kind() != reflect.Slice {
+		return zero, false
+	}
+	s := reflect.MakeSlice(t, len(values), len(values))
+	for i, v := range values {
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().AssignableTo(t.Elem()) {
+			return zero, false
+		}
+		s.Index(i).Set(rv)
+	}
+	return s.Interface().(T), true
+}
+
+// nilable t 的零值是否为 nil
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
+
+var (
+	anyType      = reflect.TypeOf((*any)(nil)).Elem()
+	anySliceType = reflect.TypeOf((*[]any)(nil)).Elem()
+)
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
+
+func describe(values []any) string {
+	types := make([]string, 0, len(values))
+	for _, v := range values {
+		types = append(types, fmt.Sprintf("%T", v))
+	}
+	return "[" + strings.Join(types, ", ") + "]"
+}
@@ -0,0 +1,136 @@
+package chainor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTyped(t *testing.T) {
+	Convey("Typed task", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor().
+			Next(Typed(func(ctx *TaskContext, in int) (int, error) {
+				c.So(in, ShouldEqual, 0)
+				return 2, nil
+			})).
+			Next(Typed(func(ctx *TaskContext, in int) (int, error) {
+				return in * 3, nil
+			}), WithParallelFunc(Typed(func(ctx *TaskContext, in int) (int, error) {
+				return in * 4, nil
+			}))).
+			Next(Typed(func(ctx *TaskContext, in []int) (string, error) {
+				total := 0
+				for _, v := range in {
+					total += v
+				}
+				c.So(total, ShouldEqual, 14)
+				return "done", nil
+			}))
+
+		InvokeTyped(chn, func(result string) {
+			c.So(result, ShouldEqual, "done")
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+
+	Convey("Typed task with mismatch", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return "a", nil
+			}).
+			Next(Typed(func(ctx *TaskContext, in int) (int, error) {
+				return in, nil
+			}), WithTaskName("double"))
+
+		Invoke(chn, nil, func(err error) {
+			c.So(errors.Is(err, ErrTypeMismatch), ShouldBeTrue)
+			c.So(strings.Contains(err.Error(), `"double"`), ShouldBeTrue)
+			wg.Done()
+		})
+
+		InvokeTyped(NewChainor().Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return "a", nil
+		}), func(result int) {
+		}, func(err error) {
+			c.So(errors.Is(err, ErrTypeMismatch), ShouldBeTrue)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+
+	Convey("Typed task with any input", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		collect := Typed(func(ctx *TaskContext, in any) (int, error) {
+			return len(in.([]any)), nil
+		})
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}).
+			Next(collect).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}, WithParallel(3)).
+			Next(collect, WithParallelFunc(collect))
+
+		Invoke(chn, func(result []any) {
+			c.So(result, ShouldResemble, []any{3, 3})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+
+	Convey("Typed task with nil input", t, func(c C) {
+		type user struct {
+			name string
+		}
+
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+
+		none := func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return nil, nil
+		}
+
+		chn := NewChainor().
+			Next(none).
+			Next(Typed(func(ctx *TaskContext, in *user) (bool, error) {
+				c.So(in, ShouldBeNil)
+				return true, nil
+			})).
+			Next(none).
+			Next(Typed(func(ctx *TaskContext, in map[string]int) (bool, error) {
+				return in == nil, nil
+			}))
+
+		InvokeTyped(chn, func(result bool) {
+			c.So(result, ShouldBeTrue)
+			wg.Done()
+		}, nil)
+
+		// 不可为 nil 的类型仍不匹配
+		InvokeTyped(NewChainor().Next(none), func(result int) {
+		}, func(err error) {
+			c.So(errors.Is(err, ErrTypeMismatch), ShouldBeTrue)
+			wg.Done()
+		})
+
+		wg.Wait()
+	})
+}
@@ -12,6 +12,31 @@ type (
 	Chainor struct {
 		opt   *option
 		queue queue.Queue
+
+		// err 构建链路时遇到的第一个错误，Build 时返回
+		err error
+		// switches 链路上的 Switch 和 Switch2，Build 时校验是否均已结束
+		switches []*switchCase
+		// forked Switch2 结束后链路已分叉，不可再注册节点
+		forked bool
+		// pools 节点协程池，与子链路（Switch 的 case、循环体等）共享
+		pools *nodePools
+	}
+
+	// Plan 由 Build 生成的执行计划，节点列表在 Build 时固定，之后对 Chainor 的修改不影响 Plan
+	//
+	// Plan 不保存任何调用状态，可被多个协程并发 Invoke
+	Plan struct {
+		opt   *option
+		nodes []*node
+		pools *nodePools
+		// labels WithLabel 标记的节点下标
+		labels map[string]int
+	}
+
+	// Invocable 可被 Invoke 触发的链路，*Chainor 与 *Plan 均实现该接口
+	Invocable interface {
+		Build() (*Plan, error)
 	}
 
 	// TaskFunc 任务函数，lastResult 为上一个任务的返回值，之所以为数组形式，是因为可能会有多个并行任务的返回值
@@ -20,7 +45,7 @@ type (
 	TaskFuncs []TaskFunc
 )
 
-// 内置的优先级常量
+// 内置的优先级常量，用于 RegisterWithPriority
 const (
 	P_Min    int = -200
 	P_Low        = -100
@@ -36,5 +61,51 @@ var (
 	// ErrNoPassed 没有任务函数命中，例如当指定 WithAnyPassed 时，没有一个任务的返回值符合预期，则会返回该错误
 	ErrNoPassed = errors.New("E_CHAINOR_NO_PASSED")
 
-	errNotDone = errors.New("not done")
+	// ErrShutdown Engine 已关闭，或执行中的链路在 Shutdown 到期时被取消
+	ErrShutdown = errors.New("E_CHAINOR_SHUTDOWN")
+
+	// ErrPoolExists 同名协程池已注册
+	ErrPoolExists = errors.New("E_CHAINOR_POOL_EXISTS")
+
+	// ErrPoolNotFound 具名协程池不存在或已释放
+	ErrPoolNotFound = errors.New("E_CHAINOR_POOL_NOT_FOUND")
+
+	// ErrTypeMismatch Typed、InvokeTyped 的结果类型与预期不符
+	ErrTypeMismatch = errors.New("E_CHAINOR_TYPE_MISMATCH")
+
+	// ErrTaskNotFound NextN、CaseN、DefaultN 引用的任务名未注册
+	ErrTaskNotFound = errors.New("E_CHAINOR_TASK_NOT_FOUND")
+
+	// ErrSwitchUnterminated Switch 或 Switch2 未以 Default、DefaultN、End 或 EndStrict 结束
+	ErrSwitchUnterminated = errors.New("E_CHAINOR_SWITCH_UNTERMINATED")
+
+	// ErrPanic 任务函数或 Predicate、WithResultMapper 等回调 panic，panic 信息经 Logger 记录
+	ErrPanic = errors.New("E_CHAINOR_PANIC")
+
+	// ErrStop 任务函数返回该错误（或 TaskContext.Finish 的返回值）时提前结束链路，后续节点不再执行，并以成功回调
+	ErrStop = errors.New("E_CHAINOR_STOP")
+
+	// ErrLabelNotFound Goto 的目标标签在当前链路及外层链路中均不存在
+	ErrLabelNotFound = errors.New("E_CHAINOR_LABEL_NOT_FOUND")
+
+	// ErrLabelDuplicated 同一条链路内 WithLabel 的标签重复
+	ErrLabelDuplicated = errors.New("E_CHAINOR_LABEL_DUPLICATED")
+
+	// ErrMaxHops 一次 Invoke 的 Goto 跳转次数超过 WithMaxHops
+	ErrMaxHops = errors.New("E_CHAINOR_MAX_HOPS")
+
+	// ErrMaxIterations While、Until 的迭代次数超过 WithMaxIterations
+	ErrMaxIterations = errors.New("E_CHAINOR_MAX_ITERATIONS")
+
+	// ErrSwitchClosed Switch 或 Switch2 结束后继续注册 case，或再次结束
+	ErrSwitchClosed = errors.New("E_CHAINOR_SWITCH_CLOSED")
+
+	// ErrNoCaseMatched 以 EndStrict 结束的 Switch、Switch2 没有 case 命中
+	ErrNoCaseMatched = errors.New("E_CHAINOR_NO_CASE_MATCHED")
+
+	// ErrOptionConflict 节点任务的可选项相互冲突，例如同时指定 WithParallel 与 WithWorkerPool
+	ErrOptionConflict = errors.New("E_CHAINOR_OPTION_CONFLICT")
+
+	// ErrChainForked Switch2 结束后链路已分叉，不可再对原 Chainor 注册节点
+	ErrChainForked = errors.New("E_CHAINOR_CHAIN_FORKED")
 )
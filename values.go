@@ -0,0 +1,139 @@
+package chainor
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// Key 带类型的 key，与 TaskContext.WithValue、Value 共用同一份存储，name 即为存储的 key
+	Key[T any] struct {
+		name string
+	}
+
+	// absent CompareAndSwap 写入的占位值，读取时视为不存在
+	absent struct{}
+
+	// deleted 隔离分支内删除的标记，读取时视为不存在，且不再读取父上下文
+	deleted struct{}
+
+	// ValueReader 可读取上下文值的对象，*TaskContext 与 Values 均实现该接口
+	ValueReader interface {
+		Value(key string) any
+	}
+
+	// Values 上下文值的只读快照，见 InvokeWithValues
+	Values struct {
+		m M
+	}
+)
+
+// NewKey 返回类型为 T 的 key
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{
+		name: name,
+	}
+}
+
+// Name key 的名称
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Set 设置 key 对应的值，全局有效，且支持并行操作
+func Set[T any](ctx *TaskContext, key Key[T], value T) {
+	ctx.WithValue(key.name, value)
+}
+
+// Get 获取 key 对应的值，不存在或类型不为 T 时返回 false
+func Get[T any](ctx ValueReader, key Key[T]) (T, bool) {
+	var zero T
+
+	tv, ok := ctx.Value(key.name).(T)
+	if !ok {
+		return zero, false
+	}
+	return tv, true
+}
+
+// MustGet 获取 key 对应的值，不存在或类型不为 T 时 panic
+func MustGet[T any](ctx ValueReader, key Key[T]) T {
+	v, ok := Get(ctx, key)
+	if !ok {
+		panic(fmt.Sprintf("chainor: value of key %q not found or not %s", key.name, typeName[T]()))
+	}
+	return v
+}
+
+// CompareAndSwap 当 key 对应的值等于 old 时替换为 new 并返回 true，key 不存在或值不相等时返回 false
+func CompareAndSwap[T comparable](ctx *TaskContext, key Key[T], old, new T) bool {
+	swapped := false
+	// 分支内先将父上下文的值放入分支内
+	ctx.s.f.ctx.local(key.name)
+	kv := ctx.s.f.ctx.keyValues
+
+	kv.Upsert(key.name, new, func(exist bool, valueInMap any, newValue any) any {
+		if !exist {
+			// Upsert 在 key 不存在时也会写入，先写入占位值，随后删除
+			return absent{}
+		}
+		if _, ok := valueInMap.(deleted); ok {
+			return valueInMap
+		}
+		if v, ok := valueInMap.(T); ok && v == old {
+			swapped = true
+			return newValue
+		}
+		return valueInMap
+	})
+
+	kv.RemoveCb(key.name, func(_ string, v any, exists bool) bool {
+		_, ok := v.(absent)
+		return exists && ok
+	})
+	if swapped {
+		ctx.s.f.ctx.touch(key.name)
+	}
+	return swapped
+}
+
+// Delete 删除 key 对应的值
+func Delete[T any](ctx *TaskContext, key Key[T]) {
+	ctx.s.f.ctx.remove(key.name)
+}
+
+func newValues(m M) Values {
+	values := Values{
+		m: make(M, len(m)),
+	}
+	for k, v := range m {
+		values.m[k] = v
+	}
+	return values
+}
+
+// Value 获取 key 对应的值，不存在时返回 nil
+func (v Values) Value(key string) any {
+	return v.m[key]
+}
+
+// Lookup 获取 key 对应的值，不存在时返回 false
+func (v Values) Lookup(key string) (any, bool) {
+	value, ok := v.m[key]
+	return value, ok
+}
+
+// Keys 返回所有 key，按字典序排列
+func (v Values) Keys() []string {
+	keys := make([]string, 0, len(v.m))
+	for k := range v.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len 值的数量
+func (v Values) Len() int {
+	return len(v.m)
+}
@@ -0,0 +1,206 @@
+package chainor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTypedValues(t *testing.T) {
+	Convey("Typed context values", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		counter := NewKey[int]("counter")
+		name := NewKey[string]("name")
+
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				Set(ctx, counter, 1)
+				ctx.WithValue("name", 5)
+				return nil, nil
+			}).
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				v, ok := Get(ctx, counter)
+				c.So(ok, ShouldBeTrue)
+				c.So(v, ShouldEqual, 1)
+				c.So(MustGet(ctx, counter), ShouldEqual, 1)
+
+				// 类型不符视为不存在
+				_, ok = Get(ctx, name)
+				c.So(ok, ShouldBeFalse)
+				c.So(func() { MustGet(ctx, name) }, ShouldPanic)
+
+				c.So(CompareAndSwap(ctx, counter, 2, 3), ShouldBeFalse)
+				c.So(CompareAndSwap(ctx, counter, 1, 2), ShouldBeTrue)
+				c.So(MustGet(ctx, counter), ShouldEqual, 2)
+
+				missing := NewKey[int]("missing")
+				c.So(CompareAndSwap(ctx, missing, 0, 1), ShouldBeFalse)
+				_, ok = Get(ctx, missing)
+				c.So(ok, ShouldBeFalse)
+				c.So(ctx.Value("missing"), ShouldBeNil)
+
+				Delete(ctx, counter)
+				_, ok = Get(ctx, counter)
+				c.So(ok, ShouldBeFalse)
+				return nil, nil
+			}, WithParallelFunc(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				Set(ctx, NewKey[int]("parallel"), 1)
+				return nil, nil
+			}))
+
+		Invoke(chn, func(result []any) {
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}
+
+func TestInvokeWithValues(t *testing.T) {
+	Convey("Invoke with values", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		user := NewKey[string]("user")
+		chn := NewChainor().
+			Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				c.So(MustGet(ctx, user), ShouldEqual, "u1")
+				ctx.WithValue("step", 1)
+				return 1, nil
+			})
+
+		InvokeWithValues(chn, func(result []any, values Values) {
+			c.So(result, ShouldResemble, []any{1})
+			c.So(values.Keys(), ShouldResemble, []string{"step", "user"})
+			c.So(values.Value("step"), ShouldEqual, 1)
+			c.So(MustGet(values, user), ShouldEqual, "u1")
+			wg.Done()
+		}, nil, WithValues(M{"user": "u1"}))
+
+		wg.Wait()
+
+		Convey("Failure with values", func(c C) {
+			wg = sync.WaitGroup{}
+			wg.Add(1)
+
+			chn = NewChainor().
+				Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					ctx.WithValue("step", 1)
+					return nil, errors.New("values err")
+				})
+
+			InvokeWithValues(chn, nil, func(err error, values Values) {
+				c.So(err.Error(), ShouldEqual, "values err")
+				v, ok := values.Lookup("step")
+				c.So(ok, ShouldBeTrue)
+				c.So(v, ShouldEqual, 1)
+				c.So(values.Len(), ShouldEqual, 1)
+				wg.Done()
+			})
+
+			wg.Wait()
+		})
+	})
+}
+
+func TestIsolate(t *testing.T) {
+	Convey("Isolated Switch2 branch", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			ctx.WithValue("tags", map[string]int{"a": 1})
+			ctx.WithValue("y", 1)
+			return 1, nil
+		}).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).Isolate().Case(1, func(c1 *Chainor) {
+			c1.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				ctx.Value("tags").(map[string]int)["a"] = 2
+				ctx.WithValue("x", 1)
+				ctx.WithValue("z", 1)
+				Delete(ctx, NewKey[int]("y"))
+				ctx.Promote("x", "y")
+				return ctx.Value("tags").(map[string]int)["a"], nil
+			})
+		}).Default(func(c2 *Chainor) {
+			c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 0, nil
+			})
+		})
+
+		InvokeWithValues(chn, func(result []any, values Values) {
+			c.So(result, ShouldResemble, []any{2})
+			c.So(values.Value("tags"), ShouldResemble, map[string]int{"a": 1})
+			c.So(values.Keys(), ShouldResemble, []string{"tags", "x"})
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+
+		Convey("Failed branch discards its values", func(c C) {
+			wg = sync.WaitGroup{}
+			wg.Add(1)
+
+			chn = NewChainor()
+			chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				return 1, nil
+			}).Switch2(func(lastResult []any) (result any) {
+				return nil
+			}).Isolate().Default(func(c2 *Chainor) {
+				c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+					ctx.WithValue("x", 1)
+					ctx.Promote("x")
+					return nil, errors.New("isolate err")
+				})
+			})
+
+			InvokeWithValues(chn, nil, func(err error, values Values) {
+				c.So(err.Error(), ShouldEqual, "isolate err")
+				c.So(values.Len(), ShouldEqual, 0)
+				wg.Done()
+			})
+
+			wg.Wait()
+		})
+	})
+
+	Convey("Isolated branches of All promote in case order", t, func(c C) {
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+
+		chn := NewChainor()
+		chn.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+			return 1, nil
+		}).Switch2(func(lastResult []any) (result any) {
+			return lastResult[0]
+		}).All().Isolate().Case(1, func(c1 *Chainor) {
+			c1.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				time.Sleep(100 * time.Millisecond)
+				ctx.WithValue("k", "first")
+				ctx.Promote("k")
+				return 1, nil
+			})
+		}).CaseIn([]any{1, 2}, func(c2 *Chainor) {
+			c2.Next(func(ctx *TaskContext, lastResult []any) (result any, err error) {
+				ctx.WithValue("k", "second")
+				ctx.Promote("k")
+				return 2, nil
+			})
+		}).End()
+
+		InvokeWithValues(chn, func(result []any, values Values) {
+			c.So(result, ShouldResemble, []any{1, 2})
+			c.So(values.Value("k"), ShouldEqual, "second")
+			wg.Done()
+		}, nil)
+
+		wg.Wait()
+	})
+}